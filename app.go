@@ -0,0 +1,178 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package chinampa
+
+import (
+	"context"
+	"io"
+	"os"
+	"text/template"
+
+	"git.rob.mx/nidito/chinampa/internal/commands"
+	"git.rob.mx/nidito/chinampa/internal/registry"
+	"git.rob.mx/nidito/chinampa/pkg/command"
+	"git.rob.mx/nidito/chinampa/pkg/env"
+	"git.rob.mx/nidito/chinampa/pkg/errors"
+	"git.rob.mx/nidito/chinampa/pkg/logger"
+	"git.rob.mx/nidito/chinampa/pkg/render"
+	"git.rob.mx/nidito/chinampa/pkg/runtime"
+	"git.rob.mx/nidito/chinampa/pkg/statuscode"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// App is a command line program, holding its root command and the commands registered to it.
+//
+// Every invocation gets its own runtime.Runtime, logger and progress screen, see command.Command.Runtime and
+// command.Command.Logger, and Run leaves process-wide settings untouched, so Apps may run several invocations at
+// once. Execute, running the program's own invocation, also sets runtime.Executable, the environment variable names
+// in the env package and the level and formatter of logrus' standard logger, for programs reading them.
+type App struct {
+	Config
+	// Root is the root command, holding global options.
+	Root *command.Command
+	// Env names the environment variables read by this program, env.Current() by default.
+	Env env.Names
+	// ErrorHandler, when set, is called by Execute with the command executed and the error it returned, instead of
+	// exiting with the status code returned by Run.
+	ErrorHandler func(cmd *cobra.Command, err error) error
+	// Errors maps sentinel errors returned by commands to status codes and messages.
+	Errors *errors.Registry
+	// HelpTemplate renders help for every command, render.NewHelpTemplate(Name) by default.
+	HelpTemplate *template.Template
+	// VersionCommandName names the version command, hidden when prefixed with an underscore.
+	VersionCommandName string
+	// LogHooks fire for the entries logged by every invocation, see command.Command.Logger.
+	LogHooks []logrus.Hook
+	registry *registry.CommandRegistry
+}
+
+// New returns an App configured with config.
+func New(config Config) *App {
+	app := &App{
+		Config:             config,
+		Root:               command.NewRoot(config.Name),
+		Env:                env.Current(),
+		Errors:             errors.NewRegistry(),
+		VersionCommandName: commands.VersionCommandName,
+		registry:           registry.New(),
+	}
+
+	app.Root.Summary = config.Summary
+	app.Root.Description = config.Description
+	if config.EnvPrefix != "" {
+		app.Env.OptionPrefix = config.EnvPrefix
+	}
+
+	return app
+}
+
+// Register adds cmds to this App.
+func (app *App) Register(cmds ...*command.Command) {
+	for _, cmd := range cmds {
+		app.registry.Register(cmd.SetBindings())
+	}
+}
+
+// Get returns the command registered to this App with the given full name, excluding the program name.
+func (app *App) Get(name string) *command.Command {
+	return app.registry.Get(name)
+}
+
+// Use adds middleware to run around the action of every command registered to this App, before their own.
+func (app *App) Use(mw ...command.Middleware) {
+	app.registry.Use(mw...)
+}
+
+// Execute runs the command given in os.Args, cancelling its context upon receiving SIGINT or SIGTERM, returning
+// once it succeeds, or exiting with the resulting status code otherwise.
+func (app *App) Execute() error {
+	return app.ExecuteContext(context.Background())
+}
+
+// ExecuteContext is like Execute, with actions receiving a context derived from ctx.
+func (app *App) ExecuteContext(ctx context.Context) error {
+	ctx, stop := withSignals(ctx)
+
+	// the program's own invocation is described by process-wide state too, for programs using the runtime and
+	// logger packages directly
+	args := os.Args[1:]
+	runtime.Executable = app.Name
+	app.Env.Use()
+	runtime.SetArgs(nil)
+
+	rt := app.runtime(args)
+	std := logrus.StandardLogger()
+	level := logger.Level(std.GetLevel())
+	std.SetFormatter(logger.NewFormatter(rt))
+	logger.SetLevel(level)
+	for _, hook := range app.LogHooks {
+		std.AddHook(hook)
+	}
+
+	cc, err := app.execute(ctx, rt, logrus.NewEntry(std), level, args, os.Stdin, os.Stdout, os.Stderr)
+	stop()
+	if app.ErrorHandler != nil {
+		return app.ErrorHandler(cc, err)
+	}
+
+	if code := app.handle(cc, err); code != statuscode.Ok {
+		os.Exit(code)
+	}
+	return nil
+}
+
+// Run runs the command given by args, excluding the program name, with stdin, stdout and stderr as its streams.
+// Help is rendered and errors logged to stderr, as Execute would, and the status code the program should exit with
+// is returned, alongside the error returned by the command. Run never exits the program.
+func (app *App) Run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) (exitCode int, err error) {
+	rt := app.runtime(args)
+	level := logger.Level(logrus.GetLevel())
+	log := logger.New(stderr, rt, level)
+	for _, hook := range app.LogHooks {
+		log.AddHook(hook)
+	}
+
+	cc, err := app.execute(ctx, rt, logrus.NewEntry(log), level, args, stdin, stdout, stderr)
+	return app.handle(cc, err), err
+}
+
+// runtime returns the Runtime of an invocation of this App with args.
+func (app *App) runtime(args []string) *runtime.Runtime {
+	return runtime.New(app.Name, app.Env, args)
+}
+
+// handle returns the status code to exit with after running cc, see errors.Registry.Handle.
+func (app *App) handle(cc *cobra.Command, err error) int {
+	if app.Errors == nil {
+		return errors.Handle(cc, err)
+	}
+	return app.Errors.Handle(cc, err)
+}
+
+// execute runs the command given by args with runtime rt, logging to log, configured at level before applying
+// verbosity, returning it alongside its error.
+func (app *App) execute(ctx context.Context, rt *runtime.Runtime, log *logrus.Entry, level logger.Level, args []string, stdin io.Reader, stdout, stderr io.Writer) (*cobra.Command, error) {
+	root := app.Root.Clone()
+	root.Summary = app.Summary
+	root.Description = app.Description
+	root.Path = []string{app.Name}
+
+	settings := registry.Settings{
+		Version:            app.Version,
+		HelpTemplate:       app.HelpTemplate,
+		VersionCommandName: app.VersionCommandName,
+		Env:                app.Env,
+	}
+	if settings.HelpTemplate == nil {
+		settings.HelpTemplate = render.NewHelpTemplate(app.Name)
+	}
+
+	ctx = logger.NewContext(runtime.NewContext(ctx, rt), log, level)
+	built := app.registry.Build(ctx, root, settings)
+	ccRoot := built.Root().Cobra
+	ccRoot.SetIn(stdin)
+	ccRoot.SetOut(stdout)
+	ccRoot.SetErr(stderr)
+	return built.Execute(ctx, args)
+}
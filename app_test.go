@@ -0,0 +1,620 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package chinampa_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+
+	. "git.rob.mx/nidito/chinampa"
+	"git.rob.mx/nidito/chinampa/pkg/command"
+	"git.rob.mx/nidito/chinampa/pkg/env"
+	"git.rob.mx/nidito/chinampa/pkg/errors"
+	"git.rob.mx/nidito/chinampa/pkg/runtime"
+	"git.rob.mx/nidito/chinampa/pkg/statuscode"
+	"github.com/sirupsen/logrus"
+)
+
+func newApp(name, cmdName string, ran map[string]string) *App {
+	app := New(Config{Name: name, Version: "1.0.0", Summary: name + " summary", Description: name + " description"})
+	app.Register(&command.Command{
+		Path:        []string{cmdName},
+		Summary:     "runs " + cmdName,
+		Description: "runs " + cmdName + " and greets someone",
+		Arguments: command.Arguments{
+			{Name: "who", Description: "who to greet", Required: true},
+		},
+		Action: func(cmd *command.Command) error {
+			ran[name] = cmd.FullName()
+			input, err := io.ReadAll(cmd.Stdin())
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(cmd.Stdout(), "%s, %s%s", cmdName, cmd.Arguments[0].ToString(), input)
+			return err
+		},
+	})
+	return app
+}
+
+func TestAppsAreIndependent(t *testing.T) {
+	ran := map[string]string{}
+	first := newApp("first", "hello", ran)
+	second := newApp("second", "goodbye", ran)
+	var stdout, stderr bytes.Buffer
+
+	if code, err := first.Run(context.Background(), []string{"hello", "world"}, strings.NewReader(""), &stdout, &stderr); err != nil || code != statuscode.Ok {
+		t.Fatalf("first app failed with %d: %s", code, err)
+	}
+
+	if code, err := second.Run(context.Background(), []string{"goodbye", "world"}, strings.NewReader(""), &stdout, &stderr); err != nil || code != statuscode.Ok {
+		t.Fatalf("second app failed with %d: %s", code, err)
+	}
+
+	third := newApp("third", "goodbye", ran)
+	if code, err := third.Run(context.Background(), []string{"hello", "world"}, strings.NewReader(""), &stdout, &stderr); err == nil || code != statuscode.NotFound {
+		t.Fatalf("third app ran a command registered to the first: %d, %v", code, err)
+	}
+
+	expected := map[string]string{"first": "first hello", "second": "second goodbye"}
+	for name, fullName := range expected {
+		if ran[name] != fullName {
+			t.Fatalf("app %s ran %q, expected %q", name, ran[name], fullName)
+		}
+	}
+
+	if first.Root.Summary != "first summary" || second.Root.Summary != "second summary" {
+		t.Fatalf("apps share a root command: %q, %q", first.Root.Summary, second.Root.Summary)
+	}
+}
+
+func TestAppRun(t *testing.T) {
+	t.Setenv(env.NoColor, "1")
+	cases := []struct {
+		Name   string
+		Args   []string
+		Stdin  string
+		Code   int
+		Err    bool
+		Stdout string
+		Stderr string
+	}{
+		{
+			Name:   "ok",
+			Args:   []string{"hello", "world"},
+			Stdin:  "!",
+			Code:   statuscode.Ok,
+			Stdout: "hello, world!",
+		},
+		{
+			Name:   "help",
+			Args:   []string{"help", "hello"},
+			Code:   statuscode.RenderHelp,
+			Stderr: "runs hello and greets someone",
+		},
+		{
+			Name:   "help flag",
+			Args:   []string{"hello", "--help"},
+			Code:   statuscode.RenderHelp,
+			Stderr: "runs hello and greets someone",
+		},
+		{
+			Name:   "unknown help topic",
+			Args:   []string{"help", "goodbye"},
+			Code:   statuscode.NotFound,
+			Err:    true,
+			Stderr: "Unknown help topic",
+		},
+		{
+			Name:   "version",
+			Args:   []string{"version"},
+			Code:   statuscode.Ok,
+			Stderr: "1.0.0",
+		},
+		{
+			Name:   "bad arguments",
+			Args:   []string{"hello"},
+			Code:   statuscode.Usage,
+			Err:    true,
+			Stderr: "Missing argument for WHO",
+		},
+		{
+			Name:   "not found",
+			Args:   []string{"goodbye"},
+			Code:   statuscode.NotFound,
+			Err:    true,
+			Stderr: "Unknown subcommand",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			app := newApp("test", "hello", map[string]string{})
+			var stdout, stderr bytes.Buffer
+			code, err := app.Run(context.Background(), c.Args, strings.NewReader(c.Stdin), &stdout, &stderr)
+			if code != c.Code {
+				t.Fatalf("unexpected exit code %d, expected %d; stderr: %s", code, c.Code, stderr.String())
+			}
+
+			if (err != nil) != c.Err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if stdout.String() != c.Stdout {
+				t.Fatalf("unexpected stdout %q, expected %q", stdout.String(), c.Stdout)
+			}
+
+			if !strings.Contains(stderr.String(), c.Stderr) {
+				t.Fatalf("stderr does not contain %q: %s", c.Stderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestAppExecuteReturnsOnSuccess(t *testing.T) {
+	args, executable, formatter := os.Args, runtime.Executable, logrus.StandardLogger().Formatter
+	t.Cleanup(func() {
+		os.Args, runtime.Executable = args, executable
+		runtime.SetArgs(nil)
+		logrus.SetFormatter(formatter)
+	})
+
+	ran := false
+	app := New(Config{Name: "test", Version: "1.0.0"})
+	app.Register(&command.Command{
+		Path:    []string{"noop"},
+		Summary: "does nothing",
+		Action: func(cmd *command.Command) error {
+			ran = true
+			return nil
+		},
+	})
+
+	// exiting would end this test's process, failing it
+	os.Args = []string{"test", "noop"}
+	if err := app.ExecuteContext(context.Background()); err != nil || !ran {
+		t.Fatalf("execute failed: %v (ran: %v)", err, ran)
+	}
+}
+
+func TestAppRunsRepeatedly(t *testing.T) {
+	t.Setenv(env.NoColor, "1")
+	app := newApp("test", "hello", map[string]string{})
+	for _, who := range []string{"world", "again"} {
+		var stdout, stderr bytes.Buffer
+		code, err := app.Run(context.Background(), []string{"hello", who}, strings.NewReader(""), &stdout, &stderr)
+		if err != nil || code != statuscode.Ok {
+			t.Fatalf("run for %s failed with %d: %v; stderr: %s", who, code, err, stderr.String())
+		}
+
+		if expected := "hello, " + who; stdout.String() != expected {
+			t.Fatalf("unexpected stdout %q, expected %q", stdout.String(), expected)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code, _ := app.Run(context.Background(), []string{"hello"}, strings.NewReader(""), &stdout, &stderr); code != statuscode.Usage {
+		t.Fatalf("run without arguments used a previous invocation's: %d; stdout: %s", code, stdout.String())
+	}
+}
+
+func TestAppRunsConcurrently(t *testing.T) {
+	t.Setenv(env.NoColor, "1")
+	app := New(Config{Name: "test"})
+	app.Register(&command.Command{
+		Path:        []string{"work"},
+		Summary:     "works",
+		Description: "works on something, logging and showing its progress",
+		Arguments:   command.Arguments{{Name: "name", Description: "what to work on", Required: true}},
+		Action: func(cmd *command.Command) error {
+			name := cmd.Arguments[0].ToString()
+			cmd.Logger().Debugf("debugging %s", name)
+			bar := cmd.Progress("working on "+name, 2)
+			bar.Add(2)
+			bar.Done()
+			return cmd.Render(map[string]any{"name": name, "verbosity": cmd.Runtime().Verbosity()})
+		},
+	})
+
+	for i := 0; i < 6; i++ {
+		name := fmt.Sprintf("run-%d", i)
+		verbosity := i % 3
+		args := []string{"work", name, "--output", "json", "--silent"}
+		if verbosity > 0 {
+			args = []string{"work", name, "--output=json", "-" + strings.Repeat("v", verbosity)}
+		}
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			for attempt := 0; attempt < 5; attempt++ {
+				var stdout, stderr bytes.Buffer
+				if code, err := app.Run(context.Background(), args, strings.NewReader(""), &stdout, &stderr); err != nil || code != statuscode.Ok {
+					t.Fatalf("run failed with %d: %v; stderr: %s", code, err, stderr.String())
+				}
+
+				result := struct {
+					Name      string `json:"name"`
+					Verbosity int    `json:"verbosity"`
+				}{}
+				if err := json.Unmarshal(stdout.Bytes(), &result); err != nil || result.Name != name || result.Verbosity != verbosity {
+					t.Fatalf("unexpected output %q: %v", stdout.String(), err)
+				}
+
+				logs := stderr.String()
+				if verbosity == 0 && logs != "" {
+					t.Fatalf("silenced run logged: %q", logs)
+				}
+
+				if verbosity > 0 && (!strings.Contains(logs, "working on "+name+": 2/2 (100%)") || strings.Count(logs, "run-") != strings.Count(logs, name)) {
+					t.Fatalf("unexpected progress logged: %q", logs)
+				}
+
+				if debugged := strings.Contains(logs, "debugging "+name); debugged != (verbosity == 2) {
+					t.Fatalf("debug entries logged: %v, with verbosity %d: %q", debugged, verbosity, logs)
+				}
+			}
+		})
+	}
+}
+
+func TestAppRunLeavesProcessStateAlone(t *testing.T) {
+	std := logrus.StandardLogger()
+	out, level, formatter := std.Out, std.GetLevel(), std.Formatter
+	names, executable := env.Current(), runtime.Executable
+
+	app := newApp("isolated", "hello", map[string]string{})
+	app.Env.Verbose = "ISOLATED_VERBOSE"
+	app.Register(&command.Command{
+		Path:        []string{"log"},
+		Summary:     "logs",
+		Description: "logs something",
+		Action: func(cmd *command.Command) error {
+			cmd.Logger().Info("logged by the invocation")
+			return nil
+		},
+	})
+	t.Setenv("ISOLATED_VERBOSE", "1")
+
+	var stdout, stderr bytes.Buffer
+	if code, err := app.Run(context.Background(), []string{"log", "--no-color"}, strings.NewReader(""), &stdout, &stderr); err != nil || code != statuscode.Ok {
+		t.Fatalf("run failed with %d: %v; stderr: %s", code, err, stderr.String())
+	}
+
+	if !strings.Contains(stderr.String(), "logged by the invocation") {
+		t.Fatalf("invocation did not log to its stderr: %q", stderr.String())
+	}
+
+	if std.Out != out || std.GetLevel() != level || std.Formatter != formatter {
+		t.Fatalf("standard logger was changed by run")
+	}
+
+	if env.Current() != names || runtime.Executable != executable {
+		t.Fatalf("process settings were changed by run: %+v, %s", env.Current(), runtime.Executable)
+	}
+}
+
+func TestAppRunLogsInternalsToInvocation(t *testing.T) {
+	std := logrus.StandardLogger()
+	out, level := std.Out, std.GetLevel()
+	var global bytes.Buffer
+	std.SetOutput(&global)
+	std.SetLevel(logrus.TraceLevel)
+	t.Cleanup(func() {
+		std.SetOutput(out)
+		std.SetLevel(level)
+	})
+
+	app := newApp("test", "hello", map[string]string{})
+	var stdout, stderr bytes.Buffer
+	if code, err := app.Run(context.Background(), []string{"hello", "world", "-vvv", "--no-color"}, strings.NewReader(""), &stdout, &stderr); err != nil || code != statuscode.Ok {
+		t.Fatalf("run failed with %d: %v; stderr: %s", code, err, stderr.String())
+	}
+
+	for _, entry := range []string{"starting execution", "running command test hello"} {
+		if !strings.Contains(stderr.String(), entry) {
+			t.Fatalf("invocation did not log %q to its stderr:\n%s", entry, stderr.String())
+		}
+	}
+
+	if global.Len() > 0 {
+		t.Fatalf("invocation logged to the standard logger:\n%s", global.String())
+	}
+}
+
+func TestAppRunVerbosity(t *testing.T) {
+	t.Setenv(env.NoColor, "1")
+	app := New(Config{Name: "test", Summary: "test summary", Description: "test description"})
+	app.Register(&command.Command{
+		Path:        []string{"log"},
+		Summary:     "logs",
+		Description: "logs its verbosity",
+		Action: func(cmd *command.Command) error {
+			cmd.Logger().Debug("debugging")
+			_, err := fmt.Fprint(cmd.Stdout(), cmd.Runtime().Verbosity())
+			return err
+		},
+	})
+
+	cases := []struct {
+		Args      []string
+		Verbosity string
+		Debug     bool
+	}{
+		{Args: []string{"log"}, Verbosity: "0"},
+		{Args: []string{"log", "-vv"}, Verbosity: "2", Debug: true},
+		{Args: []string{"log", "--verbose=2"}, Verbosity: "2", Debug: true},
+		{Args: []string{"log", "--verbose=true"}, Verbosity: "1"},
+		{Args: []string{"log", "--verbose=true", "-v"}, Verbosity: "2", Debug: true},
+		{Args: []string{"log", "-vv", "--silent"}, Verbosity: "0"},
+	}
+
+	for _, c := range cases {
+		t.Run(strings.Join(c.Args, " "), func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code, err := app.Run(context.Background(), c.Args, strings.NewReader(""), &stdout, &stderr); err != nil || code != statuscode.Ok {
+				t.Fatalf("run failed with %d: %v; stderr: %s", code, err, stderr.String())
+			}
+
+			if stdout.String() != c.Verbosity {
+				t.Fatalf("unexpected verbosity %s, expected %s", stdout.String(), c.Verbosity)
+			}
+
+			if debugged := strings.Contains(stderr.String(), "debugging"); debugged != c.Debug {
+				t.Fatalf("debug entries logged: %v, expected %v; stderr: %s", debugged, c.Debug, stderr.String())
+			}
+		})
+	}
+}
+
+func TestAppRunErrorFormat(t *testing.T) {
+	cases := []struct {
+		Name   string
+		Args   []string
+		Env    string
+		Code   int
+		Report errors.Report
+	}{
+		{
+			Name:   "missing argument",
+			Args:   []string{"hello", "--error-format=json"},
+			Code:   statuscode.Usage,
+			Report: errors.Report{Kind: errors.KindUsage, Message: "Missing argument for WHO", Code: statuscode.Usage, Command: "test hello", Argument: "who"},
+		},
+		{
+			Name:   "unknown flag",
+			Args:   []string{"hello", "world", "--error-format", "json", "--shout"},
+			Code:   statuscode.Usage,
+			Report: errors.Report{Kind: errors.KindUsage, Message: "unknown flag: --shout", Code: statuscode.Usage, Command: "test hello", Option: "shout"},
+		},
+		{
+			Name:   "not found from env",
+			Args:   []string{"hell"},
+			Env:    "json",
+			Code:   statuscode.NotFound,
+			Report: errors.Report{Kind: errors.KindNotFound, Message: "Unknown subcommand hell. Perhaps you meant hello?", Code: statuscode.NotFound, Command: "test", Suggestions: []string{"hello"}},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			t.Setenv(env.ErrorFormat, c.Env)
+			app := newApp("test", "hello", map[string]string{})
+			var stdout, stderr bytes.Buffer
+			code, _ := app.Run(context.Background(), c.Args, strings.NewReader(""), &stdout, &stderr)
+			if code != c.Code {
+				t.Fatalf("unexpected exit code %d, expected %d; stderr: %s", code, c.Code, stderr.String())
+			}
+
+			report := errors.Report{}
+			if err := json.Unmarshal(stderr.Bytes(), &report); err != nil {
+				t.Fatalf("stderr is not a json report: %s\n%s", err, stderr.String())
+			}
+
+			if !reflect.DeepEqual(report, c.Report) {
+				t.Fatalf("unexpected report:\ngot   : %+v\nwanted: %+v", report, c.Report)
+			}
+		})
+	}
+}
+
+func TestAppRunErrorFormatFlagsOfCommand(t *testing.T) {
+	t.Setenv(env.NoColor, "1")
+	t.Setenv(env.ErrorFormat, "")
+	app := New(Config{Name: "test"})
+	fail := func(cmd *command.Command) error {
+		return errors.ExitError{Code: 3, Err: fmt.Errorf("failed with %s", cmd.Arguments[0].ToString())}
+	}
+	app.Register(&command.Command{
+		Path:        []string{"fail"},
+		Summary:     "fails",
+		Description: "fails with its arguments",
+		Arguments:   command.Arguments{{Name: "args", Description: "what to fail with", Variadic: true}},
+		Action:      fail,
+	}, &command.Command{
+		Path:        []string{"convert"},
+		Summary:     "converts errors",
+		Description: "fails, having an option named like a global one",
+		Arguments:   command.Arguments{{Name: "args", Description: "what to fail with", Variadic: true}},
+		Options:     command.Options{"error-format": {Description: "the format to convert errors to"}},
+		Action:      fail,
+	})
+
+	cases := []struct {
+		Args []string
+		Code int
+		JSON bool
+	}{
+		{Args: []string{"fail", "--error-format", "json", "something"}, Code: 3, JSON: true},
+		{Args: []string{"fail", "--", "--error-format", "json"}, Code: 3},
+		{Args: []string{"fail", "--", "--error-format=json"}, Code: 3},
+		{Args: []string{"fail", "--shout", "--", "--error-format", "json"}, Code: statuscode.Usage},
+		{Args: []string{"convert", "--error-format", "json", "something"}, Code: 3},
+		{Args: []string{"convert", "--error-format", "json", "--shout"}, Code: statuscode.Usage},
+	}
+
+	for _, c := range cases {
+		t.Run(strings.Join(c.Args, " "), func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code, _ := app.Run(context.Background(), c.Args, strings.NewReader(""), &stdout, &stderr); code != c.Code {
+				t.Fatalf("unexpected exit code %d; stderr: %s", code, stderr.String())
+			}
+
+			report := errors.Report{}
+			isJSON := json.Unmarshal(stderr.Bytes(), &report) == nil
+			if isJSON != c.JSON {
+				t.Fatalf("reported as json: %v, expected %v; stderr: %s", isJSON, c.JSON, stderr.String())
+			}
+		})
+	}
+}
+
+func TestAppRunInterrupted(t *testing.T) {
+	cases := []struct {
+		Name  string
+		Cause error
+		Code  int
+	}{
+		{Name: "cancelled", Code: statuscode.Interrupted},
+		{Name: "SIGINT", Cause: errors.Signaled{Signal: syscall.SIGINT}, Code: statuscode.Interrupted},
+		{Name: "SIGTERM", Cause: errors.Signaled{Signal: syscall.SIGTERM}, Code: statuscode.Terminated},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			app := New(Config{Name: "test", Version: "1.0.0"})
+			app.Register(&command.Command{
+				Path:    []string{"wait"},
+				Summary: "waits until interrupted",
+				ContextAction: func(ctx context.Context, cmd *command.Command) error {
+					<-ctx.Done()
+					return ctx.Err()
+				},
+			})
+
+			ctx, cancel := context.WithCancelCause(context.Background())
+			cancel(c.Cause)
+			var stdout, stderr bytes.Buffer
+			code, err := app.Run(ctx, []string{"wait"}, strings.NewReader(""), &stdout, &stderr)
+			if !stderrors.Is(err, context.Canceled) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if code != c.Code {
+				t.Fatalf("unexpected exit code %d, expected %d; stderr: %s", code, c.Code, stderr.String())
+			}
+			if strings.Contains(stderr.String(), "Unknown error") || !strings.Contains(stderr.String(), "Interrupted") {
+				t.Fatalf("interrupt not reported as such: %s", stderr.String())
+			}
+		})
+	}
+}
+
+func TestAppRunExplainsGlobalOptions(t *testing.T) {
+	t.Setenv(env.NoColor, "1")
+	t.Setenv(env.Verbose, "2")
+	t.Setenv("TEST_ASSUME_YES", "1")
+	app := newApp("test", "hello", map[string]string{})
+	app.Env.AssumeYes = "TEST_ASSUME_YES"
+
+	var stdout, stderr bytes.Buffer
+	if code, err := app.Run(context.Background(), []string{"hello", "world", "--explain", "--no-input"}, strings.NewReader(""), &stdout, &stderr); err != nil || code != statuscode.Ok {
+		t.Fatalf("run failed with %d: %v; stderr: %s", code, err, stderr.String())
+	}
+
+	for _, line := range []string{
+		"--explain = true (flag --explain)",
+		"--no-input = true (flag --no-input)",
+		fmt.Sprintf("--verbose = 2 (environment variable %s)", env.Verbose),
+		"--yes = true (environment variable TEST_ASSUME_YES)",
+		fmt.Sprintf("--no-color = true (environment variable %s)", env.NoColor),
+		"--skip-validation = false (default)",
+	} {
+		if !strings.Contains(stderr.String(), line) {
+			t.Fatalf("explanation does not contain %q:\n%s", line, stderr.String())
+		}
+	}
+}
+
+func TestAppRunExplainsInvalidInput(t *testing.T) {
+	t.Setenv(env.NoColor, "1")
+	t.Setenv("TEST_TIMES", "twice")
+	app := New(Config{Name: "test", Version: "1.0.0"})
+	app.Register(&command.Command{
+		Path:    []string{"greet"},
+		Summary: "greets",
+		Options: command.Options{
+			"times": {Type: command.ValueTypeInt, Description: "how many times", Env: "TEST_TIMES"},
+		},
+		Action: func(cmd *command.Command) error {
+			t.Fatalf("action ran with invalid input")
+			return nil
+		},
+	})
+
+	var stdout, stderr bytes.Buffer
+	code, err := app.Run(context.Background(), []string{"greet", "--explain"}, strings.NewReader(""), &stdout, &stderr)
+	if err == nil || code != statuscode.Usage {
+		t.Fatalf("run succeeded with invalid input: %d %v", code, err)
+	}
+
+	for _, line := range []string{
+		"Command: test greet",
+		"--explain = true (flag --explain)",
+		"twice is not a valid int for option <times> from TEST_TIMES",
+	} {
+		if !strings.Contains(stderr.String(), line) {
+			t.Fatalf("stderr does not contain %q:\n%s", line, stderr.String())
+		}
+	}
+}
+
+func TestAppRunOutput(t *testing.T) {
+	t.Setenv(env.NoColor, "1")
+	app := New(Config{Name: "test"})
+	render := func(cmd *command.Command) error {
+		return cmd.Render([]map[string]string{{"name": "first"}, {"name": "second"}})
+	}
+	app.Register(&command.Command{
+		Path:        []string{"list"},
+		Summary:     "lists things",
+		Description: "lists things in the requested format",
+		Arguments:   command.Arguments{{Name: "filters", Description: "what to list", Variadic: true}},
+		Action:      render,
+	}, &command.Command{
+		Path:        []string{"save"},
+		Summary:     "saves things",
+		Description: "lists things, having an option named like a global one",
+		Options:     command.Options{"output": {Description: "the file to save things to"}},
+		Action:      render,
+	})
+
+	cases := []struct {
+		Args     []string
+		Expected string
+	}{
+		{Args: []string{"list", "--format", "{{ .name }}"}, Expected: "first\nsecond\n"},
+		{Args: []string{"list", "--output", "table"}, Expected: "NAME\nfirst\nsecond\n"},
+		{Args: []string{"list", "--output=template", "--format={{ json . }}"}, Expected: "{\"name\":\"first\"}\n{\"name\":\"second\"}\n"},
+		{Args: []string{"list", "--", "--output", "table"}, Expected: "first\nsecond\n"},
+		{Args: []string{"save", "--output", "file.txt"}, Expected: "first\nsecond\n"},
+	}
+
+	for _, c := range cases {
+		var stdout, stderr bytes.Buffer
+		if code, err := app.Run(context.Background(), c.Args, strings.NewReader(""), &stdout, &stderr); err != nil || code != statuscode.Ok {
+			t.Fatalf("%v failed with %d: %v; stderr: %s", c.Args, code, err, stderr.String())
+		}
+
+		if stdout.String() != c.Expected {
+			t.Fatalf("unexpected output for %v: %q, expected %q", c.Args, stdout.String(), c.Expected)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+
+/*
+Package chinampatest runs chinampa programs in tests.
+
+A Harness runs an App in process, capturing its output, logs and exit code:
+
+	h := chinampatest.New(t, app)
+	h.Setenv("MY_APP_DRY_RUN", "1")
+	h.Run("something", "zero").AssertExitCode(statuscode.Ok).AssertStdout("did something with zero")
+
+Global state, like runtime flags, environment variable names and exec.ExecFunc, is restored once each test finishes.
+*/
+package chinampatest
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"git.rob.mx/nidito/chinampa"
+	"git.rob.mx/nidito/chinampa/pkg/command"
+	"git.rob.mx/nidito/chinampa/pkg/env"
+	"git.rob.mx/nidito/chinampa/pkg/exec"
+	"git.rob.mx/nidito/chinampa/pkg/progress"
+	"git.rob.mx/nidito/chinampa/pkg/prompt"
+	"git.rob.mx/nidito/chinampa/pkg/render"
+	"git.rob.mx/nidito/chinampa/pkg/runtime"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// Harness runs an App in process.
+type Harness struct {
+	// App is the program under test.
+	App *chinampa.App
+	// Context is passed to every run, context.Background() by default.
+	Context context.Context
+	t       testing.TB
+}
+
+// New returns a Harness for app, with colors disabled, resetting global state once t finishes.
+func New(t testing.TB, app *chinampa.App) *Harness {
+	t.Helper()
+	Reset(t)
+	t.Setenv(app.Env.NoColor, "1")
+	return &Harness{App: app, Context: context.Background(), t: t}
+}
+
+// Setenv sets an environment variable for the rest of the test.
+func (h *Harness) Setenv(key, value string) *Harness {
+	h.t.Helper()
+	h.t.Setenv(key, value)
+	return h
+}
+
+// Run runs the program with args, excluding the program name, and an empty stdin.
+func (h *Harness) Run(args ...string) *Result {
+	h.t.Helper()
+	return h.RunWithStdin("", args...)
+}
+
+// RunWithStdin runs the program with args, excluding the program name, reading stdin.
+func (h *Harness) RunWithStdin(stdin string, args ...string) *Result {
+	h.t.Helper()
+	res := &Result{Args: args, t: h.t}
+
+	// runs log to their own logger, leaving the App and logrus' standard logger untouched
+	app := *h.App
+	app.LogHooks = append(append([]logrus.Hook{}, app.LogHooks...), &logHook{out: &res.logs})
+	res.ExitCode, res.Err = app.Run(h.Context, args, strings.NewReader(stdin), &res.stdout, &res.stderr)
+	return res
+}
+
+type logHook struct {
+	mu  sync.Mutex
+	out *bytes.Buffer
+}
+
+func (hook *logHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (hook *logHook) Fire(entry *logrus.Entry) error {
+	line, err := entry.String()
+	if err != nil {
+		return err
+	}
+
+	hook.mu.Lock()
+	defer hook.mu.Unlock()
+	_, err = hook.out.WriteString(line)
+	return err
+}
+
+// Reset restores global state changed during a test once t finishes: the runtime's parsed flags and executable name,
+// environment variable names, the help template, exec.ExecFunc, prompt.PrompterFunc, progress.TerminalFunc, and the
+// level, output and hooks of logrus.
+func Reset(t testing.TB) {
+	t.Helper()
+	names := env.Current()
+	executable := runtime.Executable
+	helpTemplate := render.TemplateCommandHelp
+	execFunc := exec.ExecFunc
+	prompterFunc := prompt.PrompterFunc
+	terminalFunc := progress.TerminalFunc
+	std := logrus.StandardLogger()
+	level := std.GetLevel()
+	out := std.Out
+	hooks := logrus.LevelHooks{}
+	for level, levelHooks := range std.Hooks {
+		hooks[level] = append(hooks[level], levelHooks...)
+	}
+
+	t.Cleanup(func() {
+		runtime.SetArgs(nil)
+		names.Use()
+		runtime.Executable = executable
+		render.TemplateCommandHelp = helpTemplate
+		exec.ExecFunc = execFunc
+		prompt.PrompterFunc = prompterFunc
+		progress.TerminalFunc = terminalFunc
+		std.SetLevel(level)
+		std.SetOutput(out)
+		std.ReplaceHooks(hooks)
+	})
+}
+
+// Args makes the runtime package look up global flags in args, instead of os.Args, until t finishes.
+func Args(t testing.TB, args ...string) {
+	t.Helper()
+	if args == nil {
+		args = []string{}
+	}
+	runtime.SetArgs(args)
+	t.Cleanup(func() { runtime.SetArgs(nil) })
+}
+
+// Env replaces the environment with vars until t finishes.
+func Env(t testing.TB, vars map[string]string) {
+	t.Helper()
+	previous := os.Environ()
+	os.Clearenv()
+	for key, value := range vars {
+		os.Setenv(key, value) // nolint:errcheck
+	}
+	runtime.ResetParsedFlagsCache()
+
+	t.Cleanup(func() {
+		os.Clearenv()
+		for _, entry := range previous {
+			parts := strings.SplitN(entry, "=", 2)
+			os.Setenv(parts[0], parts[1]) // nolint:errcheck
+		}
+		runtime.ResetParsedFlagsCache()
+	})
+}
+
+// ExecFunc runs subprocesses, see exec.ExecFunc.
+type ExecFunc func(ctx context.Context, env []string, executable string, args ...string) (bytes.Buffer, bytes.Buffer, error)
+
+// StubExec replaces exec.ExecFunc, used by Script value sources, with fn until t finishes.
+func StubExec(t testing.TB, fn ExecFunc) {
+	t.Helper()
+	previous := exec.ExecFunc
+	exec.ExecFunc = fn
+	t.Cleanup(func() { exec.ExecFunc = previous })
+}
+
+// StubOutput replaces exec.ExecFunc with one printing stdout and returning err until t finishes.
+func StubOutput(t testing.TB, stdout string, err error) {
+	t.Helper()
+	StubExec(t, func(ctx context.Context, env []string, executable string, args ...string) (bytes.Buffer, bytes.Buffer, error) {
+		return *bytes.NewBufferString(stdout), bytes.Buffer{}, err
+	})
+}
+
+// StubFunc makes source resolve its values by calling fn, instead of its Func, Script or Command, until t finishes.
+func StubFunc(t testing.TB, source *command.ValueSource, fn command.CompletionFunc) {
+	t.Helper()
+	previous := source.Func
+	source.Func = fn
+	t.Cleanup(func() { source.Func = previous })
+}
+
+// StubValues makes source resolve to values until t finishes.
+func StubValues(t testing.TB, source *command.ValueSource, values ...string) {
+	t.Helper()
+	StubFunc(t, source, func(cmd *command.Command, currentValue, config string) ([]string, cobra.ShellCompDirective, error) {
+		return values, cobra.ShellCompDirectiveDefault, nil
+	})
+}
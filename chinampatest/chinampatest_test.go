@@ -0,0 +1,276 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package chinampatest_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"git.rob.mx/nidito/chinampa"
+	. "git.rob.mx/nidito/chinampa/chinampatest"
+	"git.rob.mx/nidito/chinampa/pkg/command"
+	"git.rob.mx/nidito/chinampa/pkg/exec"
+	"git.rob.mx/nidito/chinampa/pkg/logger"
+	"git.rob.mx/nidito/chinampa/pkg/runtime"
+	"git.rob.mx/nidito/chinampa/pkg/statuscode"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func testApp() *chinampa.App {
+	app := chinampa.New(chinampa.Config{Name: "test", Summary: "tests things", Description: "a program to test"})
+	app.Register(&command.Command{
+		Path:        []string{"greet"},
+		Summary:     "greets someone",
+		Description: "greets someone from a list of known people",
+		Arguments: command.Arguments{
+			{
+				Name:        "who",
+				Description: "who to greet",
+				Required:    true,
+				Values:      &command.ValueSource{Script: "cat people.txt"},
+			},
+		},
+		Options: command.Options{
+			"greeting": {
+				Description: "how to greet",
+				Default:     "hello",
+				Values:      &command.ValueSource{Static: &[]string{"hello", "howdy"}},
+			},
+			"mood": {
+				Description: "how to feel",
+				Default:     "happy",
+				Values: &command.ValueSource{
+					Func: func(cmd *command.Command, currentValue, config string) ([]string, cobra.ShellCompDirective, error) {
+						return nil, cobra.ShellCompDirectiveError, fmt.Errorf("not stubbed")
+					},
+				},
+			},
+		},
+		Action: func(cmd *command.Command) error {
+			cmd.Logger().Info("greeting someone")
+			input := ""
+			if buf, err := io.ReadAll(cmd.Stdin()); err == nil {
+				input = string(buf)
+			}
+			_, err := fmt.Fprintf(cmd.Stdout(), "%s, %s%s", cmd.Options["greeting"].ToString(), cmd.Arguments[0].ToString(), input)
+			return err
+		},
+	})
+	return app
+}
+
+func TestHarness(t *testing.T) {
+	app := testApp()
+	h := New(t, app)
+	StubOutput(t, "world\nmoon\n", nil)
+	StubValues(t, app.Get("greet").Options["mood"].Values, "happy", "sad")
+
+	h.Run("greet", "world").
+		AssertExitCode(statuscode.Ok).
+		AssertSuccess().
+		AssertStdout("hello, world").
+		AssertLogContains("greeting someone")
+
+	h.RunWithStdin("!", "greet", "--greeting", "howdy", "moon").
+		AssertExitCode(statuscode.Ok).
+		AssertStdout("howdy, moon!")
+
+	h.Run("greet", "sun").
+		AssertExitCode(statuscode.Usage).
+		AssertError("sun is not a valid value").
+		AssertStderrContains("sun is not a valid value")
+
+	h.Run("greet", "world", "--mood", "angry").
+		AssertExitCode(statuscode.Usage).
+		AssertError("angry is not a valid value")
+
+	h.Run("greet", "--help").
+		AssertExitCode(statuscode.RenderHelp).
+		AssertStderrContains("greets someone from a list of known people")
+
+	level := logrus.GetLevel()
+	h.Run("greet", "world", "-vvv").AssertExitCode(statuscode.Ok)
+	if logrus.GetLevel() != level {
+		t.Fatalf("log level was not restored after running")
+	}
+}
+
+func TestStubExec(t *testing.T) {
+	var ran []string
+	StubExec(t, func(ctx context.Context, env []string, executable string, args ...string) (bytes.Buffer, bytes.Buffer, error) {
+		ran = append([]string{executable}, args...)
+		return *bytes.NewBufferString("stubbed\n"), bytes.Buffer{}, nil
+	})
+
+	values, _, err := exec.Exec("test", []string{"bash", "-c", "exit 2"}, []string{}, time.Second, logger.Main)
+	if err != nil {
+		t.Fatalf("stubbed exec failed: %s", err)
+	}
+
+	if strings.Join(values, ",") != "stubbed" || strings.Join(ran, " ") != "bash -c exit 2" {
+		t.Fatalf("unexpected values %v from running %v", values, ran)
+	}
+}
+
+func TestEnvAndArgs(t *testing.T) {
+	t.Run("set", func(t *testing.T) {
+		Env(t, map[string]string{"CHINAMPATEST": "1"})
+		Args(t, "--silent")
+		if !runtime.SilenceEnabled() {
+			t.Fatalf("args were not used")
+		}
+
+		if os.Getenv("CHINAMPATEST") != "1" || os.Getenv("HOME") != "" {
+			t.Fatalf("environment was not replaced")
+		}
+	})
+
+	if runtime.SilenceEnabled() {
+		t.Fatalf("args were not reset")
+	}
+
+	if os.Getenv("CHINAMPATEST") != "" {
+		t.Fatalf("environment was not restored")
+	}
+}
+
+func TestComplete(t *testing.T) {
+	app := testApp()
+	h := New(t, app)
+	StubOutput(t, "world\nmoon\n", nil)
+	StubValues(t, app.Get("greet").Options["mood"].Values, "happy", "sad")
+
+	h.Complete("test greet ").
+		AssertValues("world", "moon").
+		AssertActiveHelp("who to greet").
+		AssertDirective(cobra.ShellCompDirectiveDefault)
+
+	h.Complete("test greet --greeting h").
+		AssertValues("hello", "howdy").
+		AssertDirective(cobra.ShellCompDirectiveDefault)
+
+	h.CompleteAt("test greet --mood s world", len("test greet --mood s")).
+		AssertValues("sad").
+		AssertActiveHelp("how to feel")
+
+	h.Complete("test gr").
+		AssertCandidate("greet", "greets someone").
+		AssertDirective(cobra.ShellCompDirectiveNoFileComp)
+}
+
+func TestPrompts(t *testing.T) {
+	app := testApp()
+	h := New(t, app)
+	StubOutput(t, "world\nmoon\n", nil)
+	StubValues(t, app.Get("greet").Options["mood"].Values, "happy", "sad")
+
+	prompter := StubPrompts(t, "moon")
+	h.Run("greet", "--no-input").
+		AssertExitCode(statuscode.Usage).
+		AssertError("Missing argument for WHO")
+
+	h.Run("greet").
+		AssertExitCode(statuscode.Ok).
+		AssertStdout("hello, moon")
+
+	if len(prompter.Asked) != 1 || prompter.Asked[0] != "who (who to greet)" {
+		t.Fatalf("unexpected prompts: %v", prompter.Asked)
+	}
+
+	if options := prompter.Options["who (who to greet)"]; len(options) != 2 || options[0] != "world" {
+		t.Fatalf("unexpected options: %v", options)
+	}
+
+	prompter = StubPrompts(t, "moon")
+	h.Run("greet", "--skip-validation").
+		AssertExitCode(statuscode.Ok).
+		AssertStdout("hello, moon")
+	if len(prompter.Asked) != 1 {
+		t.Fatalf("skipping validation skipped prompts: %v", prompter.Asked)
+	}
+
+	StubPrompts(t)
+	h.Run("greet", "world").AssertExitCode(statuscode.Ok)
+}
+
+func TestConfirm(t *testing.T) {
+	app := chinampa.New(chinampa.Config{Name: "test", Summary: "tests things", Description: "a program to test"})
+	deleted := []string{}
+	app.Register(&command.Command{
+		Path:        []string{"delete"},
+		Summary:     "deletes a thing",
+		Description: "deletes a thing forever",
+		Arguments:   command.Arguments{{Name: "name", Description: "the thing to delete", Required: true}},
+		Destructive: &command.Confirmation{Argument: "name"},
+		Action: func(cmd *command.Command) error {
+			deleted = append(deleted, cmd.Arguments[0].ToString())
+			return nil
+		},
+	})
+	app.Register(&command.Command{
+		Path:        []string{"purge"},
+		Summary:     "purges everything",
+		Description: "purges every thing forever",
+		Destructive: &command.Confirmation{},
+		Action: func(cmd *command.Command) error {
+			deleted = append(deleted, "*")
+			return nil
+		},
+	})
+	h := New(t, app)
+
+	h.Run("delete", "thing").
+		AssertExitCode(statuscode.Usage).
+		AssertError("delete is destructive and can only be confirmed on a terminal").
+		AssertStderrContains("Pass --yes")
+
+	h.Run("delete", "thing", "--yes").AssertExitCode(statuscode.Ok)
+	h.Run("delete", "thing", "--yes=true").AssertExitCode(statuscode.Ok)
+	h.Run("delete", "--", "--yes").
+		AssertExitCode(statuscode.Usage).
+		AssertError("can only be confirmed on a terminal")
+
+	prompter := StubPrompts(t, "thing", "other", "y", "", "--yes")
+	h.Run("delete", "thing").AssertExitCode(statuscode.Ok)
+	h.Run("delete", "thing").
+		AssertExitCode(statuscode.Cancelled).
+		AssertError("declined running test delete")
+	h.Run("purge").AssertExitCode(statuscode.Ok)
+	h.Run("purge").AssertExitCode(statuscode.Cancelled)
+	h.Run("delete", "--", "--yes").AssertExitCode(statuscode.Ok)
+
+	if len(prompter.Asked) != 5 || prompter.Asked[0] != `Are you sure you want to run test delete? Type "thing" to confirm` {
+		t.Fatalf("unexpected prompts: %q", prompter.Asked)
+	}
+
+	h.Run("purge", "--no-input").AssertExitCode(statuscode.Usage)
+
+	if strings.Join(deleted, " ") != "thing thing thing * --yes" {
+		t.Fatalf("unexpected deletions: %v", deleted)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	app := chinampa.New(chinampa.Config{Name: "test", Summary: "tests things", Description: "a program to test"})
+	app.Register(&command.Command{
+		Path:        []string{"work"},
+		Summary:     "works",
+		Description: "works on things, forgetting to say when it's done",
+		Action: func(cmd *command.Command) error {
+			cmd.Progress("working", 2).Add(2)
+			return nil
+		},
+	})
+
+	New(t, app).Run("work").
+		AssertExitCode(statuscode.Ok).
+		AssertLogContains("working…").
+		AssertLogContains("working: 2/2 (100%), done in 0s")
+}
@@ -0,0 +1,84 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package chinampatest
+
+import (
+	"strings"
+	"testing"
+
+	"git.rob.mx/nidito/chinampa"
+	"github.com/spf13/cobra"
+)
+
+// CompletionResult holds the completion offered to shells for a command line.
+type CompletionResult struct {
+	*chinampa.Completion
+	// Line is the command line completed, including the program name.
+	Line string
+	t    testing.TB
+}
+
+// Complete returns the completion offered to shells for line, with the cursor at its end. line must include the
+// program name, and a trailing space to complete a new word.
+func (h *Harness) Complete(line string) *CompletionResult {
+	h.t.Helper()
+	return h.CompleteAt(line, len(line))
+}
+
+// CompleteAt returns the completion offered to shells for line, with the cursor at byte offset cursor.
+func (h *Harness) CompleteAt(line string, cursor int) *CompletionResult {
+	h.t.Helper()
+	completion, err := h.App.Complete(h.Context, line, cursor)
+	if err != nil {
+		h.t.Fatalf("could not complete %q: %s", line, err)
+	}
+	return &CompletionResult{Completion: completion, Line: line, t: h.t}
+}
+
+func (res *CompletionResult) fail(format string, args ...any) {
+	res.t.Helper()
+	args = append(args, res.Line, res.Candidates, res.ActiveHelp, res.Directives())
+	res.t.Fatalf(format+"\nline: %q\ncandidates: %q\nactive help: %q\ndirectives: %s", args...)
+}
+
+// AssertValues fails the test unless the values of candidates are exactly values, in order.
+func (res *CompletionResult) AssertValues(values ...string) *CompletionResult {
+	res.t.Helper()
+	if strings.Join(res.Values(), "\n") != strings.Join(values, "\n") || len(res.Candidates) != len(values) {
+		res.fail("expected candidates %q", values)
+	}
+	return res
+}
+
+// AssertCandidate fails the test unless a candidate with value and description is offered.
+func (res *CompletionResult) AssertCandidate(value, description string) *CompletionResult {
+	res.t.Helper()
+	for _, candidate := range res.Candidates {
+		if candidate.Value == value && candidate.Description == description {
+			return res
+		}
+	}
+	res.fail("expected candidate %q with description %q", value, description)
+	return res
+}
+
+// AssertActiveHelp fails the test unless an active help message contains message.
+func (res *CompletionResult) AssertActiveHelp(message string) *CompletionResult {
+	res.t.Helper()
+	for _, help := range res.ActiveHelp {
+		if strings.Contains(help, message) {
+			return res
+		}
+	}
+	res.fail("expected active help containing %q", message)
+	return res
+}
+
+// AssertDirective fails the test unless the directive is exactly directive.
+func (res *CompletionResult) AssertDirective(directive cobra.ShellCompDirective) *CompletionResult {
+	res.t.Helper()
+	if res.Directive != directive {
+		res.fail("expected directive %d", directive)
+	}
+	return res
+}
@@ -0,0 +1,95 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package chinampatest
+
+import (
+	"io"
+	"sync"
+	"testing"
+
+	"git.rob.mx/nidito/chinampa/pkg/prompt"
+)
+
+// Prompter answers prompts in order, see StubPrompts.
+type Prompter struct {
+	// Answers are given to prompts in order.
+	Answers []string
+	// Asked holds the label of every prompt, in order.
+	Asked []string
+	// Options holds the options offered by every Select prompt, keyed by label.
+	Options map[string][]string
+	mu      sync.Mutex
+	t       testing.TB
+}
+
+// StubPrompts makes commands prompt for missing values, as if running on a terminal, answering them in order
+// with answers, until t finishes. Tests fail if an answer is missing, not among the options of a Select prompt
+// or, for Input and Secret prompts, invalid.
+func StubPrompts(t testing.TB, answers ...string) *Prompter {
+	t.Helper()
+	prompter := &Prompter{Answers: answers, Asked: []string{}, Options: map[string][]string{}, t: t}
+	previous := prompt.PrompterFunc
+	prompt.PrompterFunc = func(in io.Reader, out io.Writer) prompt.Prompter { return prompter }
+	t.Cleanup(func() { prompt.PrompterFunc = previous })
+	return prompter
+}
+
+func (p *Prompter) next(label string) (string, error) {
+	p.t.Helper()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.Asked = append(p.Asked, label)
+	if len(p.Answers) == 0 {
+		p.t.Errorf("no answer left for prompt %q", label)
+		return "", prompt.ErrInterrupted
+	}
+
+	answer := p.Answers[0]
+	p.Answers = p.Answers[1:]
+	return answer, nil
+}
+
+// Select answers with the next answer, failing the test unless it is one of options.
+func (p *Prompter) Select(label string, options []string) (string, error) {
+	p.t.Helper()
+	p.mu.Lock()
+	p.Options[label] = options
+	p.mu.Unlock()
+
+	answer, err := p.next(label)
+	if err != nil {
+		return "", err
+	}
+
+	for _, option := range options {
+		if option == answer {
+			return answer, nil
+		}
+	}
+
+	p.t.Errorf("answer %q for prompt %q is not one of %q", answer, label, options)
+	return "", prompt.ErrInterrupted
+}
+
+// Input answers with the next answer, failing the test if validate returns an error.
+func (p *Prompter) Input(label string, validate func(value string) error) (string, error) {
+	p.t.Helper()
+	answer, err := p.next(label)
+	if err != nil {
+		return "", err
+	}
+
+	if validate != nil {
+		if err := validate(answer); err != nil {
+			p.t.Errorf("answer %q for prompt %q is invalid: %s", answer, label, err)
+			return "", err
+		}
+	}
+	return answer, nil
+}
+
+// Secret answers like Input.
+func (p *Prompter) Secret(label string, validate func(value string) error) (string, error) {
+	p.t.Helper()
+	return p.Input(label, validate)
+}
@@ -0,0 +1,109 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package chinampatest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// Result holds the outcome of running a program.
+type Result struct {
+	// Args the program ran with, excluding the program name.
+	Args []string
+	// ExitCode is the status code the program would exit with.
+	ExitCode int
+	// Err is the error returned by the command that ran, if any.
+	Err    error
+	stdout bytes.Buffer
+	stderr bytes.Buffer
+	logs   bytes.Buffer
+	t      testing.TB
+}
+
+// Stdout returns what the program wrote to stdout.
+func (res *Result) Stdout() string {
+	return res.stdout.String()
+}
+
+// Stderr returns what the program wrote to stderr, including help and log entries.
+func (res *Result) Stderr() string {
+	return res.stderr.String()
+}
+
+// Log returns the log entries written while the program ran.
+func (res *Result) Log() string {
+	return res.logs.String()
+}
+
+func (res *Result) fail(format string, args ...any) {
+	res.t.Helper()
+	args = append(args, res.Args, res.ExitCode, res.Stdout(), res.Stderr())
+	res.t.Fatalf(format+"\nargs: %q\nexit code: %d\nstdout: %s\nstderr: %s", args...)
+}
+
+// AssertExitCode fails the test unless the program exited with code.
+func (res *Result) AssertExitCode(code int) *Result {
+	res.t.Helper()
+	if res.ExitCode != code {
+		res.fail("expected exit code %d", code)
+	}
+	return res
+}
+
+// AssertSuccess fails the test if the command returned an error.
+func (res *Result) AssertSuccess() *Result {
+	res.t.Helper()
+	if res.Err != nil {
+		res.fail("unexpected error: %s", res.Err)
+	}
+	return res
+}
+
+// AssertError fails the test unless the command returned an error containing message.
+func (res *Result) AssertError(message string) *Result {
+	res.t.Helper()
+	if res.Err == nil {
+		res.fail("expected an error containing %q", message)
+	} else if !strings.Contains(res.Err.Error(), message) {
+		res.fail("expected an error containing %q, got: %s", message, res.Err)
+	}
+	return res
+}
+
+// AssertStdout fails the test unless stdout equals expected.
+func (res *Result) AssertStdout(expected string) *Result {
+	res.t.Helper()
+	if res.Stdout() != expected {
+		res.fail("expected stdout %q", expected)
+	}
+	return res
+}
+
+// AssertStdoutContains fails the test unless stdout contains expected.
+func (res *Result) AssertStdoutContains(expected string) *Result {
+	res.t.Helper()
+	if !strings.Contains(res.Stdout(), expected) {
+		res.fail("expected stdout to contain %q", expected)
+	}
+	return res
+}
+
+// AssertStderrContains fails the test unless stderr contains expected.
+func (res *Result) AssertStderrContains(expected string) *Result {
+	res.t.Helper()
+	if !strings.Contains(res.Stderr(), expected) {
+		res.fail("expected stderr to contain %q", expected)
+	}
+	return res
+}
+
+// AssertLogContains fails the test unless a log entry contains expected.
+func (res *Result) AssertLogContains(expected string) *Result {
+	res.t.Helper()
+	if !strings.Contains(res.Log(), expected) {
+		res.fail("expected log to contain %q, log: %s", expected, res.Log())
+	}
+	return res
+}
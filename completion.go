@@ -0,0 +1,174 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package chinampa
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// activeHelpMarker prefixes active help entries in the output of cobra.ShellCompRequestCmd.
+const activeHelpMarker = "_activeHelp_ "
+
+// directiveNames maps shell completion directives to their names.
+var directiveNames = []struct {
+	Directive cobra.ShellCompDirective
+	Name      string
+}{
+	{cobra.ShellCompDirectiveError, "Error"},
+	{cobra.ShellCompDirectiveNoSpace, "NoSpace"},
+	{cobra.ShellCompDirectiveNoFileComp, "NoFileComp"},
+	{cobra.ShellCompDirectiveFilterFileExt, "FilterFileExt"},
+	{cobra.ShellCompDirectiveFilterDirs, "FilterDirs"},
+	{cobra.ShellCompDirectiveKeepOrder, "KeepOrder"},
+}
+
+// Candidate is a value offered to complete a command line.
+type Candidate struct {
+	Value       string
+	Description string
+}
+
+// Completion holds what shells are offered when completing a command line.
+type Completion struct {
+	// Args are the words before the one being completed, excluding the program name.
+	Args []string
+	// ToComplete is the partially typed word being completed, if any.
+	ToComplete string
+	// Candidates are the values offered, alongside their descriptions.
+	Candidates []Candidate
+	// ActiveHelp holds messages for the user, shown by shells supporting them.
+	ActiveHelp []string
+	// Directive tells shells how to treat candidates.
+	Directive cobra.ShellCompDirective
+}
+
+// Values returns the value of every candidate.
+func (c *Completion) Values() []string {
+	values := make([]string, len(c.Candidates))
+	for idx, candidate := range c.Candidates {
+		values[idx] = candidate.Value
+	}
+	return values
+}
+
+// HasDirective tells if directive is set.
+func (c *Completion) HasDirective(directive cobra.ShellCompDirective) bool {
+	if directive == cobra.ShellCompDirectiveDefault {
+		return c.Directive == directive
+	}
+	return c.Directive&directive != 0
+}
+
+// Directives returns the names of the directives set, i.e. NoSpace or FilterFileExt, or Default if none are.
+func (c *Completion) Directives() []string {
+	names := []string{}
+	for _, d := range directiveNames {
+		if c.Directive&d.Directive != 0 {
+			names = append(names, d.Name)
+		}
+	}
+
+	if len(names) == 0 {
+		names = append(names, "Default")
+	}
+	return names
+}
+
+// Complete returns the completion offered to shells for line, as typed up to cursor, a byte offset into line.
+// The first word of line is the program name.
+func (app *App) Complete(ctx context.Context, line string, cursor int) (*Completion, error) {
+	if cursor < 0 || cursor > len(line) {
+		return nil, fmt.Errorf("cursor %d is outside of command line %q", cursor, line)
+	}
+
+	words := splitCommandLine(line[:cursor])
+	if len(words) < 2 {
+		// only the program name is typed, so complete an empty word after it
+		words = append(words, "")
+	}
+
+	completion := &Completion{
+		Args:       words[1 : len(words)-1],
+		ToComplete: words[len(words)-1],
+		Candidates: []Candidate{},
+		ActiveHelp: []string{},
+	}
+
+	var stdout, stderr bytes.Buffer
+	args := append([]string{cobra.ShellCompRequestCmd}, words[1:]...)
+	if _, err := app.Run(ctx, args, strings.NewReader(""), &stdout, &stderr); err != nil {
+		return nil, fmt.Errorf("could not complete %q: %w", line, err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n")
+	last := lines[len(lines)-1]
+	if !strings.HasPrefix(last, ":") {
+		return nil, fmt.Errorf("could not complete %q, no directive found in output: %s", line, stdout.String())
+	}
+
+	directive, err := strconv.Atoi(last[1:])
+	if err != nil {
+		return nil, fmt.Errorf("could not complete %q, unknown directive %s: %w", line, last, err)
+	}
+	completion.Directive = cobra.ShellCompDirective(directive)
+
+	for _, entry := range lines[:len(lines)-1] {
+		if help, isHelp := strings.CutPrefix(entry, activeHelpMarker); isHelp {
+			completion.ActiveHelp = append(completion.ActiveHelp, help)
+			continue
+		}
+
+		value, description, _ := strings.Cut(entry, "\t")
+		completion.Candidates = append(completion.Candidates, Candidate{Value: value, Description: description})
+	}
+
+	return completion, nil
+}
+
+// splitCommandLine splits line into words as a shell would, honoring quotes and backslash escapes. A trailing
+// space yields an empty last word.
+func splitCommandLine(line string) []string {
+	words := []string{}
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+
+	for _, char := range line {
+		switch {
+		case escaped:
+			word.WriteRune(char)
+			escaped = false
+		case char == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			} else {
+				word.WriteRune(char)
+			}
+		case char == '\'' || char == '"':
+			quote = char
+			inWord = true
+		case char == ' ' || char == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(char)
+			inWord = true
+		}
+	}
+
+	// the word being completed is always last, even if empty
+	return append(words, word.String())
+}
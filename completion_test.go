@@ -0,0 +1,154 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package chinampa_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	. "git.rob.mx/nidito/chinampa"
+	"git.rob.mx/nidito/chinampa/pkg/command"
+	"git.rob.mx/nidito/chinampa/pkg/env"
+	"github.com/spf13/cobra"
+)
+
+func completionApp() *App {
+	app := New(Config{Name: "test", Summary: "test summary", Description: "test description"})
+	app.Register(&command.Command{
+		Path:        []string{"paint"},
+		Summary:     "paints things",
+		Description: "paints things of a color",
+		Arguments: command.Arguments{
+			{
+				Name:        "color",
+				Description: "the color to paint with",
+				Required:    true,
+				Values:      &command.ValueSource{Static: &[]string{"red", "green", "blue"}},
+			},
+			{
+				Name:        "file",
+				Description: "the file to paint",
+				Required:    true,
+				Values:      &command.ValueSource{Files: &[]string{"png"}},
+			},
+		},
+		Options: command.Options{
+			"finish": {
+				Description: "the finish to apply",
+				Values: &command.ValueSource{
+					Func: func(cmd *command.Command, currentValue, config string) ([]string, cobra.ShellCompDirective, error) {
+						return []string{"matte", "gloss"}, cobra.ShellCompDirectiveNoSpace, nil
+					},
+				},
+			},
+		},
+		Action: func(cmd *command.Command) error { return nil },
+	})
+	return app
+}
+
+func TestComplete(t *testing.T) {
+	t.Setenv(env.NoColor, "1")
+	cases := []struct {
+		Name       string
+		Line       string
+		Cursor     int
+		Args       []string
+		ToComplete string
+		Values     []string
+		Help       string
+		Directives []string
+	}{
+		{
+			Name:       "subcommands",
+			Line:       "test pa",
+			Cursor:     -1,
+			Args:       []string{},
+			ToComplete: "pa",
+			Values:     []string{"paint"},
+			Directives: []string{"NoFileComp"},
+		},
+		{
+			Name:       "static argument",
+			Line:       "test paint ",
+			Cursor:     -1,
+			Args:       []string{"paint"},
+			Values:     []string{"red", "green", "blue"},
+			Help:       "the color to paint with",
+			Directives: []string{"Default"},
+		},
+		{
+			Name:       "filtered argument",
+			Line:       "test paint gr",
+			Cursor:     -1,
+			Args:       []string{"paint"},
+			ToComplete: "gr",
+			Values:     []string{"green"},
+			Directives: []string{"Default"},
+		},
+		{
+			Name:       "cursor",
+			Line:       "test paint blue file.png",
+			Cursor:     len("test paint bl"),
+			Args:       []string{"paint"},
+			ToComplete: "bl",
+			Values:     []string{"blue"},
+			Directives: []string{"Default"},
+		},
+		{
+			Name:       "file argument",
+			Line:       `test paint "blue" `,
+			Cursor:     -1,
+			Args:       []string{"paint", "blue"},
+			Values:     []string{"png"},
+			Help:       "the file to paint",
+			Directives: []string{"FilterFileExt"},
+		},
+		{
+			Name:       "option",
+			Line:       "test paint --finish ",
+			Cursor:     -1,
+			Args:       []string{"paint", "--finish"},
+			Values:     []string{"matte", "gloss"},
+			Help:       "the finish to apply",
+			Directives: []string{"NoSpace"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			cursor := c.Cursor
+			if cursor < 0 {
+				cursor = len(c.Line)
+			}
+
+			completion, err := completionApp().Complete(context.Background(), c.Line, cursor)
+			if err != nil {
+				t.Fatalf("could not complete: %s", err)
+			}
+
+			if strings.Join(completion.Args, " ") != strings.Join(c.Args, " ") || completion.ToComplete != c.ToComplete {
+				t.Fatalf("unexpected args %q and word %q", completion.Args, completion.ToComplete)
+			}
+
+			if strings.Join(completion.Values(), ",") != strings.Join(c.Values, ",") {
+				t.Fatalf("unexpected candidates %q, expected %q", completion.Values(), c.Values)
+			}
+
+			if c.Help != "" && strings.Join(completion.ActiveHelp, "\n") != c.Help {
+				t.Fatalf("unexpected active help %q, expected %q", completion.ActiveHelp, c.Help)
+			}
+
+			if strings.Join(completion.Directives(), ",") != strings.Join(c.Directives, ",") {
+				t.Fatalf("unexpected directives %q, expected %q", completion.Directives(), c.Directives)
+			}
+		})
+	}
+}
+
+func TestCompleteOutOfBounds(t *testing.T) {
+	if _, err := completionApp().Complete(context.Background(), "test", 10); err == nil {
+		t.Fatalf("completed with a cursor outside of the line")
+	}
+}
@@ -3,6 +3,8 @@ module git.rob.mx/nidito/chinampa
 go 1.22
 
 require (
+	github.com/BurntSushi/toml v1.4.0
+	github.com/alecthomas/chroma/v2 v2.13.0
 	github.com/charmbracelet/glamour v0.7.0
 	github.com/fatih/color v1.17.0
 	github.com/go-playground/validator/v10 v10.20.0
@@ -14,7 +16,6 @@ require (
 )
 
 require (
-	github.com/alecthomas/chroma/v2 v2.13.0 // indirect
 	github.com/aymanbagabas/go-osc52/v2 v2.0.1 // indirect
 	github.com/aymerick/douceur v0.2.0 // indirect
 	github.com/dlclark/regexp2 v1.11.0 // indirect
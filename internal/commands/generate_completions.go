@@ -3,28 +3,29 @@
 package commands
 
 import (
-	"os"
-
 	"github.com/spf13/cobra"
 )
 
-var GenerateCompletions = &cobra.Command{
-	Use:               "__generate_completions [bash|zsh|fish]",
-	Short:             "Outputs a shell-specific script for autocompletions that can be piped into a file",
-	Hidden:            true,
-	DisableAutoGenTag: true,
-	SilenceUsage:      true,
-	Args:              cobra.ExactArgs(1),
-	ValidArgs:         []string{"bash", "fish", "zsh"},
-	RunE: func(cmd *cobra.Command, args []string) (err error) {
-		switch args[0] {
-		case "bash":
-			err = cmd.Root().GenBashCompletionV2(os.Stdout, true)
-		case "zsh":
-			err = cmd.Root().GenZshCompletion(os.Stdout)
-		case "fish":
-			err = cmd.Root().GenFishCompletion(os.Stdout, true)
-		}
-		return
-	},
+// NewGenerateCompletions returns a hidden command that outputs shell completion scripts.
+func NewGenerateCompletions() *cobra.Command {
+	return &cobra.Command{
+		Use:               "__generate_completions [bash|zsh|fish]",
+		Short:             "Outputs a shell-specific script for autocompletions that can be piped into a file",
+		Hidden:            true,
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		Args:              cobra.ExactArgs(1),
+		ValidArgs:         []string{"bash", "fish", "zsh"},
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			switch args[0] {
+			case "bash":
+				err = cmd.Root().GenBashCompletionV2(cmd.OutOrStdout(), true)
+			case "zsh":
+				err = cmd.Root().GenZshCompletion(cmd.OutOrStdout())
+			case "fish":
+				err = cmd.Root().GenFishCompletion(cmd.OutOrStdout(), true)
+			}
+			return
+		},
+	}
 }
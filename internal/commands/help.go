@@ -4,13 +4,11 @@ package commands
 
 import (
 	"fmt"
-	"os"
 	"strings"
 
 	_c "git.rob.mx/nidito/chinampa/internal/constants"
 	"git.rob.mx/nidito/chinampa/pkg/env"
-	"git.rob.mx/nidito/chinampa/pkg/statuscode"
-	"github.com/sirupsen/logrus"
+	"git.rob.mx/nidito/chinampa/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
@@ -22,61 +20,59 @@ func cobraCommandFullName(c *cobra.Command) []string {
 	return name
 }
 
-var Help = &cobra.Command{
-	Use:   _c.HelpCommandName,
-	Short: "Display usage information for any command",
-	Long: `Provides documentation for any command known to ﹅@chinampa@﹅, including its known arguments and options.
+// NewHelp returns the help command for the program called executable, reading the environment variables named by names.
+func NewHelp(executable string, names env.Names) *cobra.Command {
+	return &cobra.Command{
+		Use:   _c.HelpCommandName,
+		Short: "Display usage information for any command",
+		Long: `Provides documentation for any command known to ﹅` + executable + `﹅, including its known arguments and options.
 
 ### Colorized output
 
-By default, and unless ﹅` + env.NoColor + `﹅ is set, ﹅@chinampa@ help﹅ will query the environment variable ﹅COLORFGBG﹅ to decide which style to use when rendering help, unless if ﹅` + env.HelpStyle + `﹅ is set to any of the following values: **light**, **dark**, **markdown**, or **auto**. 24-bit color is available when ﹅COLORTERM﹅ is set to ﹅truecolor﹅.`,
-	ValidArgsFunction: func(c *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		var completions []string
-		cmd, _, e := c.Root().Find(args)
-		if e != nil {
-			return nil, cobra.ShellCompDirectiveNoFileComp
-		}
-		if cmd == nil {
-			// Root help command.
-			cmd = c.Root()
-		}
-		for _, subCmd := range cmd.Commands() {
-			if subCmd.IsAvailableCommand() || subCmd.Name() == _c.HelpCommandName {
-				if strings.HasPrefix(subCmd.Name(), toComplete) {
-					completions = append(completions, fmt.Sprintf("%s\t%s", subCmd.Name(), subCmd.Short))
-				}
+By default, and unless ﹅` + names.NoColor + `﹅ is set, ﹅` + executable + ` help﹅ will query the environment variable ﹅COLORFGBG﹅ to decide which style to use when rendering help, unless if ﹅` + names.HelpStyle + `﹅ is set to any of the following values: **light**, **dark**, **markdown**, or **auto**. 24-bit color is available when ﹅COLORTERM﹅ is set to ﹅truecolor﹅.`,
+		ValidArgsFunction: func(c *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			var completions []string
+			cmd, _, e := c.Root().Find(args)
+			if e != nil {
+				return nil, cobra.ShellCompDirectiveNoFileComp
 			}
-		}
-		return completions, cobra.ShellCompDirectiveNoFileComp
-	},
-	Run: func(c *cobra.Command, args []string) {
-		if len(args) > 0 && c != nil && c.Name() != args[len(args)-1] {
-			c, topicArgs, err := c.Root().Find(args)
-			if err == nil && c != nil && len(topicArgs) == 0 {
-				// exact command help
-				cobra.CheckErr(c.Help())
-				os.Exit(statuscode.RenderHelp)
-				return
+			if cmd == nil {
+				// Root help command.
+				cmd = c.Root()
 			}
-
-			if err != nil {
-				logrus.Error(err)
-				os.Exit(statuscode.ProgrammerError)
+			for _, subCmd := range cmd.Commands() {
+				if subCmd.IsAvailableCommand() || subCmd.Name() == _c.HelpCommandName {
+					if strings.HasPrefix(subCmd.Name(), toComplete) {
+						completions = append(completions, fmt.Sprintf("%s\t%s", subCmd.Name(), subCmd.Short))
+					}
+				}
 			}
+			return completions, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			if len(args) > 0 && c != nil && c.Name() != args[len(args)-1] {
+				c, topicArgs, err := c.Root().Find(args)
+				if err == nil && c != nil && len(topicArgs) == 0 {
+					// exact command help
+					return c.Help()
+				}
 
-			fullName := strings.Join(cobraCommandFullName(c), " ")
-			cobra.CheckErr(c.Help())
-			if len(topicArgs) > 0 {
-				logrus.Errorf("Unknown help topic \"%s\" for %s", topicArgs[0], fullName)
-			} else {
-				logrus.Errorf("Unknown help topic \"%s\"", args[0])
-			}
-			os.Exit(statuscode.NotFound)
-		}
+				if err != nil {
+					return err
+				}
 
-		c.InitDefaultHelpFlag() // make possible 'help' flag to be shown
-		cobra.CheckErr(c.Help())
+				fullName := strings.Join(cobraCommandFullName(c), " ")
+				if err := c.Help(); err != nil {
+					return err
+				}
+				if len(topicArgs) > 0 {
+					return errors.NotFound{Msg: fmt.Sprintf("Unknown help topic \"%s\" for %s", topicArgs[0], fullName)}
+				}
+				return errors.NotFound{Msg: fmt.Sprintf("Unknown help topic \"%s\"", args[0])}
+			}
 
-		os.Exit(statuscode.RenderHelp)
-	},
+			c.InitDefaultHelpFlag() // make possible 'help' flag to be shown
+			return c.Help()
+		},
+	}
 }
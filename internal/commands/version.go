@@ -3,36 +3,38 @@
 package commands
 
 import (
-	"os"
+	"strings"
 
 	"git.rob.mx/nidito/chinampa/pkg/logger"
-	"git.rob.mx/nidito/chinampa/pkg/statuscode"
 	"github.com/spf13/cobra"
 )
 
+// VersionCommandName is the default name of the version command, hidden when prefixed with an underscore.
 var VersionCommandName = "version"
-var Version = &cobra.Command{
-	Use:               VersionCommandName,
-	Short:             "Display program version",
-	Hidden:            false,
-	DisableAutoGenTag: true,
-	SilenceUsage:      true,
-	RunE: func(cmd *cobra.Command, args []string) error {
-		output := cmd.ErrOrStderr()
-		version := cmd.Root().Annotations["version"]
-		if cmd.CalledAs() == "" {
-			// user asked for --version directly
-			output = cmd.OutOrStderr()
-			version += "\n"
-		}
 
-		_, err := output.Write([]byte(version))
-		if err != nil {
-			logger.Main.Errorf("version error: %s", err)
-			return err
-		}
+// NewVersion returns a command called name that displays the program version.
+func NewVersion(name string) *cobra.Command {
+	return &cobra.Command{
+		Use:               name,
+		Short:             "Display program version",
+		Hidden:            strings.HasPrefix(name, "_"),
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output := cmd.ErrOrStderr()
+			version := cmd.Root().Annotations["version"]
+			if cmd.CalledAs() == "" {
+				// user asked for --version directly
+				version += "\n"
+			}
 
-		os.Exit(statuscode.Ok)
-		return nil
-	},
+			_, err := output.Write([]byte(version))
+			if err != nil {
+				logger.FromContext(cmd.Context()).Errorf("version error: %s", err)
+				return err
+			}
+
+			return nil
+		},
+	}
 }
@@ -8,7 +8,6 @@ import (
 
 	"git.rob.mx/nidito/chinampa/pkg/command"
 	"git.rob.mx/nidito/chinampa/pkg/errors"
-	"git.rob.mx/nidito/chinampa/pkg/runtime"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
@@ -29,15 +28,16 @@ func newCobraRoot(root *command.Command) *cobra.Command {
 		Args: func(cmd *cobra.Command, args []string) error {
 			if err := cobra.OnlyValidArgs(cmd, args); err != nil {
 				suggestions := []string{}
+				names := cmd.SuggestionsFor(args[len(args)-1])
 				bold := color.New(color.Bold)
-				for _, l := range cmd.SuggestionsFor(args[len(args)-1]) {
+				for _, l := range names {
 					suggestions = append(suggestions, bold.Sprint(l))
 				}
 				errMessage := fmt.Sprintf("Unknown subcommand %s", bold.Sprint(strings.Join(args, " ")))
 				if len(suggestions) > 0 {
 					errMessage += ". Perhaps you meant " + strings.Join(suggestions, ", ") + "?"
 				}
-				return errors.NotFound{Msg: errMessage, Group: []string{}}
+				return errors.NotFound{Msg: errMessage, Group: []string{}, Suggestions: names}
 			}
 			return nil
 		},
@@ -54,9 +54,9 @@ func newCobraRoot(root *command.Command) *cobra.Command {
 	}
 }
 
-func ToCobra(cmd *command.Command, globalOptions command.Options, parent *cobra.Command) *cobra.Command {
+func (r *CommandRegistry) ToCobra(cmd *command.Command, globalOptions command.Options, parent *cobra.Command) *cobra.Command {
 	localName := cmd.Name()
-	useSpec := []string{localName, "[options]"}
+	useSpec := append([]string{localName, "[options]"}, cmd.OptionsUsage()...)
 	for idx, arg := range cmd.Arguments {
 		if arg == nil {
 			useSpec = append(useSpec, fmt.Sprintf("could not parse spec for argument %d of command %s", idx, cmd.FullName()))
@@ -75,21 +75,13 @@ func ToCobra(cmd *command.Command, globalOptions command.Options, parent *cobra.
 		Annotations: map[string]string{
 			ContextKeyRuntimeIndex: cmd.FullName(),
 		},
-		Args: func(cc *cobra.Command, supplied []string) error {
-			skipValidation, _ := cc.Flags().GetBool("skip-validation")
-			if !skipValidation && runtime.ValidationEnabled() {
-				if err := cmd.Arguments.Parse(supplied); err != nil {
-					return err
-				}
-				return cmd.Arguments.AreValid()
-			}
-			return nil
-		},
+		// arguments are parsed and validated by cmd.Run, for every invocation
+		Args: cobra.ArbitraryArgs,
 		RunE: cmd.Run,
 	}
 
 	cc.SetFlagErrorFunc(func(c *cobra.Command, e error) error {
-		return errors.BadArguments{Msg: e.Error()}
+		return errors.FlagError(e)
 	})
 
 	cc.ValidArgsFunction = cmd.Arguments.CompletionFunction
@@ -102,7 +94,7 @@ func ToCobra(cmd *command.Command, globalOptions command.Options, parent *cobra.
 			continue
 		}
 		if err := cc.RegisterFlagCompletionFunc(name, opt.CompletionFunction); err != nil {
-			log.Errorf("Failed setting up autocompletion for option <%s> of command <%s>", name, cmd.FullName())
+			r.log().Errorf("Failed setting up autocompletion for option <%s> of command <%s>", name, cmd.FullName())
 		}
 	}
 	parent.AddCommand(cc)
@@ -110,8 +102,8 @@ func ToCobra(cmd *command.Command, globalOptions command.Options, parent *cobra.
 	cmdGlobalOptions := globalOptions
 	if parent != cc.Root() {
 		cmdGlobalOptions = subOptions(globalOptions)
-		log.Tracef("Adding subflags from %s to child %s", parent.Name(), cmd.FullName())
-		if p := FromCobra(parent); p != nil {
+		r.log().Tracef("Adding subflags from %s to child %s", parent.Name(), cmd.FullName())
+		if p := r.fromCobra(parent); p != nil {
 			for key, opt := range p.Options {
 				cmdGlobalOptions[key] = opt
 			}
@@ -123,10 +115,29 @@ func ToCobra(cmd *command.Command, globalOptions command.Options, parent *cobra.
 	return cc
 }
 
+// ForCobra returns the registry executing the command tree cc belongs to, if any.
+func ForCobra(cc *cobra.Command) *CommandRegistry {
+	executing.RLock()
+	defer executing.RUnlock()
+	return executing.roots[cc.Root()]
+}
+
+// FromCobra returns the command bound to cc by the registry executing it, if any.
 func FromCobra(cc *cobra.Command) *command.Command {
+	if r := ForCobra(cc); r != nil {
+		return r.fromCobra(cc)
+	}
+	return nil
+}
+
+func (r *CommandRegistry) fromCobra(cc *cobra.Command) *command.Command {
+	if r.root != nil && cc == r.root.Cobra {
+		return r.root
+	}
+
 	rtidx, hasAnnotation := cc.Annotations[ContextKeyRuntimeIndex]
 	if hasAnnotation {
-		return Get(rtidx)
+		return r.Get(rtidx)
 	}
 	return nil
 }
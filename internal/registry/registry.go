@@ -3,17 +3,20 @@
 package registry
 
 import (
+	"context"
 	"fmt"
-	"os"
 	"sort"
 	"strings"
+	"sync"
+	"text/template"
 
 	"git.rob.mx/nidito/chinampa/internal/commands"
 	"git.rob.mx/nidito/chinampa/pkg/command"
+	"git.rob.mx/nidito/chinampa/pkg/env"
 	"git.rob.mx/nidito/chinampa/pkg/errors"
 	"git.rob.mx/nidito/chinampa/pkg/logger"
-	"git.rob.mx/nidito/chinampa/pkg/runtime"
 	"github.com/fatih/color"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
@@ -21,13 +24,14 @@ import (
 // ContextKeyRuntimeIndex is the string key used to store context in a cobra Command.
 const ContextKeyRuntimeIndex = "x-chinampa-runtime-index" // nolint:gosec
 
-var ErrorHandler = errors.HandleCobraExit
+// component names this package in log entries.
+const component = "registry"
 
-var log = logger.Sub("registry")
-
-var registry = &CommandRegistry{
-	kv: map[string]*command.Command{},
-}
+// executing maps the cobra roots of executing registries back to them.
+var executing = struct {
+	sync.RWMutex
+	roots map[*cobra.Command]*CommandRegistry
+}{roots: map[*cobra.Command]*CommandRegistry{}}
 
 type ByPath []*command.Command
 
@@ -35,31 +39,78 @@ func (cmds ByPath) Len() int           { return len(cmds) }
 func (cmds ByPath) Swap(i, j int)      { cmds[i], cmds[j] = cmds[j], cmds[i] }
 func (cmds ByPath) Less(i, j int) bool { return cmds[i].FullName() < cmds[j].FullName() }
 
+// Settings configure the command tree built for an invocation.
+type Settings struct {
+	// Version of the program, the version command is added when not empty.
+	Version string
+	// HelpTemplate renders help for every command, see command.Command.SetHelpTemplate.
+	HelpTemplate *template.Template
+	// VersionCommandName names the version command, hidden when prefixed with an underscore.
+	VersionCommandName string
+	// Env names the environment variables mentioned by the help command.
+	Env env.Names
+}
+
+// CommandRegistry holds the commands of an application, and turns them into a cobra command tree upon execution.
 type CommandRegistry struct {
-	kv     map[string]*command.Command
-	byPath []*command.Command
+	settings   Settings
+	kv         map[string]*command.Command
+	byPath     []*command.Command
+	middleware []command.Middleware
+	root       *command.Command
+	// ctx belongs to the invocation a registry was built for.
+	ctx context.Context
 }
 
-func Register(cmd *command.Command) {
-	log.Tracef("adding to registry: %s", cmd.FullName())
-	registry.kv[cmd.FullName()] = cmd
+// New returns an empty registry.
+func New() *CommandRegistry {
+	return &CommandRegistry{
+		kv: map[string]*command.Command{},
+	}
 }
 
-func Get(id string) *command.Command {
-	return registry.kv[id]
+// Use adds middleware to run around every command's action.
+func (r *CommandRegistry) Use(mw ...command.Middleware) {
+	r.middleware = append(r.middleware, mw...)
+}
+
+// middlewareFor returns global middleware, followed by that of the group parents of cmd.
+func (r *CommandRegistry) middlewareFor(cmd *command.Command) []command.Middleware {
+	chain := append([]command.Middleware{}, r.middleware...)
+	for idx := 1; idx < len(cmd.Path); idx++ {
+		if parent := r.Get(strings.Join(cmd.Path[0:idx], " ")); parent != nil {
+			chain = append(chain, parent.Middleware...)
+		}
+	}
+	return chain
 }
 
-func CommandList() []*command.Command {
-	if len(registry.byPath) == 0 {
+func (r *CommandRegistry) Register(cmd *command.Command) {
+	r.kv[cmd.FullName()] = cmd
+	r.byPath = nil
+}
+
+func (r *CommandRegistry) Get(id string) *command.Command {
+	return r.kv[id]
+}
+
+func (r *CommandRegistry) CommandList() []*command.Command {
+	if len(r.byPath) == 0 {
 		list := []*command.Command{}
-		for _, v := range registry.kv {
+		for _, v := range r.kv {
 			list = append(list, v)
 		}
 		sort.Sort(ByPath(list))
-		registry.byPath = list
+		r.byPath = list
 	}
 
-	return registry.byPath
+	return r.byPath
+}
+
+func (r *CommandRegistry) setHelpTemplate(cmd *command.Command) {
+	if r.settings.HelpTemplate != nil {
+		cmd.SetHelpTemplate(r.settings.HelpTemplate)
+	}
 }
 
 func subOptions(m command.Options) command.Options {
@@ -70,14 +121,51 @@ func subOptions(m command.Options) command.Options {
 	return m2
 }
 
-func Execute(version string) error {
-	log.Debug("starting execution")
-	cmdRoot := command.Root
-	ccRoot := newCobraRoot(command.Root)
+// Build returns a registry holding copies of the registered commands, bound to a new cobra command tree with cmdRoot
+// at its root and configured by settings, leaving this registry's commands untouched so they may be built and run
+// again. Building is logged to the logger of the invocation ctx belongs to, see logger.FromContext.
+func (r *CommandRegistry) Build(ctx context.Context, cmdRoot *command.Command, settings Settings) *CommandRegistry {
+	if settings.VersionCommandName == "" {
+		settings.VersionCommandName = commands.VersionCommandName
+	}
+
+	built := &CommandRegistry{
+		settings:   settings,
+		kv:         make(map[string]*command.Command, len(r.kv)),
+		middleware: append([]command.Middleware{}, r.middleware...),
+		root:       cmdRoot,
+		ctx:        ctx,
+	}
+
+	for id, cmd := range r.kv {
+		built.kv[id] = cmd.Clone()
+	}
+
+	built.build()
+	return built
+}
+
+// log returns the logger of the invocation this registry was built for, or logger.Main before it's built.
+func (r *CommandRegistry) log() *logrus.Entry {
+	return logger.SubFromContext(r.ctx, component)
+}
+
+// Root returns the root command of a built registry.
+func (r *CommandRegistry) Root() *command.Command {
+	return r.root
+}
+
+func (r *CommandRegistry) build() {
+	r.log().Debug("building command tree")
+	cmdRoot := r.root
+	r.setHelpTemplate(cmdRoot)
+	ccRoot := newCobraRoot(cmdRoot)
 	ccRoot.CompletionOptions.HiddenDefaultCmd = true
+
+	help := commands.NewHelp(cmdRoot.Name(), r.settings.Env)
 	globalOptions := command.Options{}
 	cmdRoot.FlagSet().VisitAll(func(f *pflag.Flag) {
-		opt := command.Root.Options[f.Name]
+		opt := cmdRoot.Options[f.Name]
 		if f.Name == "version" {
 			ccRoot.Flags().AddFlag(f)
 		} else {
@@ -86,47 +174,46 @@ func Execute(version string) error {
 		}
 
 		if err := ccRoot.RegisterFlagCompletionFunc(f.Name, opt.CompletionFunction); err != nil {
-			log.Errorf("Failed setting up autocompletion for option <%s> of command <%s>", f.Name, cmdRoot.FullName())
+			r.log().Errorf("Failed setting up autocompletion for option <%s> of command <%s>", f.Name, cmdRoot.FullName())
 		}
 	})
 
-	if version != "" {
-		name := commands.VersionCommandName
-		ccRoot.Annotations["version"] = version
-		commands.Version.Hidden = strings.HasPrefix(name, "_")
-		commands.Version.Use = name
-		ccRoot.AddCommand(commands.Version)
+	if r.settings.Version != "" {
+		ccRoot.Annotations["version"] = r.settings.Version
+		ccRoot.AddCommand(commands.NewVersion(r.settings.VersionCommandName))
 	}
-	ccRoot.AddCommand(commands.GenerateCompletions)
+	ccRoot.AddCommand(commands.NewGenerateCompletions())
 
 	ccRoot.SetHelpFunc(cmdRoot.HelpRenderer(globalOptions))
-	for _, cmd := range CommandList() {
+	for _, cmd := range r.CommandList() {
 		cmd := cmd
 		container := ccRoot
 		for idx, cp := range cmd.Path {
 			if idx == len(cmd.Path)-1 {
-				if cmd.Action != nil && cmd.Cobra == nil {
+				if cmd.HasAction() && cmd.Cobra == nil {
 					// nil actions come when the current command consists only
 					// of metadata for a "group parent" command
 					// and we don't wanna cobraize it like a regular, actionable one
-					leaf := ToCobra(cmd, globalOptions, container)
-					log.Tracef("cobra: %s => %s", leaf.Name(), container.CommandPath())
+					cmd.InheritMiddleware(r.middlewareFor(cmd)...)
+					r.setHelpTemplate(cmd)
+					leaf := r.ToCobra(cmd, globalOptions, container)
+					r.log().Tracef("cobra: %s => %s", leaf.Name(), container.CommandPath())
 					break
 				}
-				log.Tracef("Found command with no action: %s, assuming group parent action", cmd.Path)
+				r.log().Tracef("Found command with no action: %s, assuming group parent action", cmd.Path)
 			}
 
 			query := []string{cp}
 			found := false
 			if len(query) == 1 && query[0] == "help" {
-				container = commands.Help
+				container = help
 				continue
 			}
 
 			for _, sub := range container.Commands() {
 				if sub.Name() == cp {
-					if parent := FromCobra(container); parent != nil {
-						log.Tracef("pflags to %s from %s", sub.Name(), parent.FullName())
+					if parent := r.fromCobra(container); parent != nil {
+						r.log().Tracef("pflags to %s from %s", sub.Name(), parent.FullName())
 						fs := parent.FlagSet()
 						sub.PersistentFlags().AddFlagSet(fs)
 					}
@@ -142,9 +229,9 @@ func Execute(version string) error {
 
 				cmdGlobalOptions := globalOptions
 
-				groupParent := Get(groupName)
+				groupParent := r.Get(groupName)
 				if groupParent == nil {
-					log.Tracef("creating group parent for %s", groupPath)
+					r.log().Tracef("creating group parent for %s", groupPath)
 					groupParent = &command.Command{
 						Path:        cleanPath,
 						Summary:     fmt.Sprintf("%s subcommands", groupName),
@@ -156,10 +243,11 @@ func Execute(version string) error {
 						groupParent.Summary = cmd.Summary
 						groupParent.Description = cmd.Description
 					}
-					Register(groupParent)
+					r.Register(groupParent)
 				} else {
-					log.Tracef("using pre-existing group parent for %s (%s)", groupPath, groupParent.Path)
+					r.log().Tracef("using pre-existing group parent for %s (%s)", groupPath, groupParent.Path)
 				}
+				r.setHelpTemplate(groupParent)
 
 				cc := &cobra.Command{
 					Use:                        cp,
@@ -178,8 +266,9 @@ func Execute(version string) error {
 						}
 
 						suggestions := []string{}
+						names := cmd.SuggestionsFor(args[len(args)-1])
 						bold := color.New(color.Bold)
-						for _, l := range cmd.SuggestionsFor(args[len(args)-1]) {
+						for _, l := range names {
 							suggestions = append(suggestions, bold.Sprint(l))
 						}
 						last := len(args) - 1
@@ -188,7 +277,7 @@ func Execute(version string) error {
 						if len(suggestions) > 0 {
 							errMessage += ". Perhaps you meant " + strings.Join(suggestions, ", ") + "?"
 						}
-						return errors.NotFound{Msg: errMessage, Group: []string{}}
+						return errors.NotFound{Msg: errMessage, Group: []string{}, Suggestions: names}
 					},
 					RunE: func(cc *cobra.Command, args []string) error {
 						if len(args) == 0 {
@@ -205,12 +294,12 @@ func Execute(version string) error {
 				if len(groupParent.Options) > 0 {
 					fs := cmd.FlagSet()
 					cc.PersistentFlags().AddFlagSet(fs)
-					log.Debugf("adding sub-global option set to %s", groupName)
+					r.log().Debugf("adding sub-global option set to %s", groupName)
 				}
 
 				if container != cc.Root() {
 					cmdGlobalOptions = subOptions(globalOptions)
-					if p := FromCobra(container); p != nil {
+					if p := r.fromCobra(container); p != nil {
 						for key, opt := range p.Options {
 							cmdGlobalOptions[key] = opt
 						}
@@ -219,7 +308,7 @@ func Execute(version string) error {
 				cc.PersistentFlags().AddFlagSet(container.PersistentFlags())
 
 				cc.SetHelpFunc(groupParent.HelpRenderer(cmdGlobalOptions))
-				cc.SetHelpCommand(commands.Help)
+				cc.SetHelpCommand(help)
 				container.AddCommand(cc)
 				container = cc
 			}
@@ -228,10 +317,24 @@ func Execute(version string) error {
 		cmd.Path = append(cmdRoot.Path, cmd.Path...)
 	}
 	cmdRoot.SetCobra(ccRoot)
-	commands.Help.Long = strings.ReplaceAll(commands.Help.Long, "@chinampa@", runtime.Executable)
-	ccRoot.SetHelpCommand(commands.Help)
+	ccRoot.SetHelpCommand(help)
+}
 
-	current, remaining, err := ccRoot.Find(os.Args[1:])
+// Execute runs the command given by args from the tree of a built registry, returning it alongside its error.
+func (r *CommandRegistry) Execute(ctx context.Context, args []string) (*cobra.Command, error) {
+	log := logger.SubFromContext(ctx, component)
+	log.Debug("starting execution")
+	ccRoot := r.root.Cobra
+	executing.Lock()
+	executing.roots[ccRoot] = r
+	executing.Unlock()
+	defer func() {
+		executing.Lock()
+		delete(executing.roots, ccRoot)
+		executing.Unlock()
+	}()
+
+	current, remaining, err := ccRoot.Find(args)
 	if err != nil {
 		current = ccRoot
 	}
@@ -243,5 +346,11 @@ func Execute(version string) error {
 	}
 	log.Debugf("exec: calling %s", current.CommandPath())
 
-	return ErrorHandler(current, current.Execute())
+	ccRoot.SetContext(ctx)
+	ccRoot.SetArgs(args)
+	executed, err := ccRoot.ExecuteC()
+	if executed == nil {
+		executed = current
+	}
+	return executed, err
 }
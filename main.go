@@ -3,17 +3,33 @@
 package chinampa
 
 import (
+	"context"
+
 	"git.rob.mx/nidito/chinampa/internal/commands"
 	"git.rob.mx/nidito/chinampa/internal/registry"
 	"git.rob.mx/nidito/chinampa/pkg/command"
-	"git.rob.mx/nidito/chinampa/pkg/runtime"
+	"git.rob.mx/nidito/chinampa/pkg/env"
+	"git.rob.mx/nidito/chinampa/pkg/errors"
+	"git.rob.mx/nidito/chinampa/pkg/render"
 	"github.com/spf13/cobra"
 )
 
+// defaultApp backs the package-level functions, with command.Root as its root command.
+var defaultApp = &App{
+	Root:               command.Root,
+	Env:                env.Current(),
+	Errors:             errors.Default,
+	VersionCommandName: commands.VersionCommandName,
+	registry:           registry.New(),
+}
+
 func Register(cmds ...*command.Command) {
-	for _, cmd := range cmds {
-		registry.Register(cmd.SetBindings())
-	}
+	defaultApp.Register(cmds...)
+}
+
+// Use adds middleware to run around the action of every registered command, before their own.
+func Use(mw ...command.Middleware) {
+	defaultApp.Use(mw...)
 }
 
 type Config struct {
@@ -21,20 +37,34 @@ type Config struct {
 	Version     string
 	Summary     string
 	Description string
+	// EnvPrefix derives environment variables for options, see env.OptionPrefix.
+	EnvPrefix string
 }
 
 func SetVersionCommandName(name string) {
-	commands.VersionCommandName = name
+	defaultApp.VersionCommandName = name
 }
 
 func SetErrorHandler(handlerFunc func(cmd *cobra.Command, err error) error) {
-	registry.ErrorHandler = handlerFunc
+	defaultApp.ErrorHandler = handlerFunc
 }
 
+// Execute runs the command given in os.Args, cancelling its context upon receiving SIGINT or SIGTERM.
 func Execute(config Config) error {
-	runtime.Executable = config.Name
-	command.Root.Summary = config.Summary
-	command.Root.Description = config.Description
-	command.Root.Path = []string{runtime.Executable}
-	return registry.Execute(config.Version)
+	return ExecuteContext(context.Background(), config)
+}
+
+// ExecuteContext is like Execute, with actions receiving a context derived from ctx.
+func ExecuteContext(ctx context.Context, config Config) error {
+	defaultApp.Config = config
+	// env names may have been changed by the program since the default app was created
+	defaultApp.Env = env.Current()
+	if config.EnvPrefix != "" {
+		defaultApp.Env.OptionPrefix = config.EnvPrefix
+	}
+
+	// programs may still replace the default help template through the render package
+	defaultApp.HelpTemplate = render.TemplateCommandHelp
+
+	return defaultApp.ExecuteContext(ctx)
 }
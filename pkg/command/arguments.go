@@ -22,6 +22,16 @@ func contains(haystack []string, needle string) bool {
 // Arguments is an ordered list of Argument.
 type Arguments []*Argument
 
+// Get returns the argument called name, or nil if none exists.
+func (args *Arguments) Get(name string) *Argument {
+	for _, arg := range *args {
+		if arg != nil && arg.Name == name {
+			return arg
+		}
+	}
+	return nil
+}
+
 func (args *Arguments) AllKnown() map[string]any {
 	col := map[string]any{}
 	for _, arg := range *args {
@@ -38,31 +48,20 @@ func (args *Arguments) AllKnownStr() map[string]string {
 	return col
 }
 
-func anySliceToStringSlice(src any) []string {
-	res := []string{}
-	switch d := src.(type) {
-	case []string:
-		res = d
-	case []any:
-		for _, valI := range d {
-			res = append(res, valI.(string))
-		}
-	}
-	return res
-}
-
 func (args *Arguments) Parse(supplied []string) error {
+	problems := []error{}
 	parsed := []string{}
 	for idx, arg := range *args {
 		argumentProvided := idx < len(supplied)
+		arg.source = Source{Kind: SourceDefault}
 
 		if !argumentProvided {
 			if arg.Default != nil {
 				if arg.Variadic {
-					defaultSlice := anySliceToStringSlice(arg.Default)
+					defaultSlice := formatEach(arg.Type, arg.Default)
 					arg.provided = &defaultSlice
 				} else {
-					defaultString := arg.Default.(string)
+					defaultString := arg.Type.Format(arg.Default)
 					if defaultString != "" {
 						arg.provided = &[]string{defaultString}
 					}
@@ -77,23 +76,30 @@ func (args *Arguments) Parse(supplied []string) error {
 		} else {
 			arg.SetValue([]string{supplied[idx]})
 		}
+		arg.source = Source{Kind: SourceArgument, Name: fmt.Sprint(idx + 1)}
+
+		for _, value := range *arg.provided {
+			if _, err := arg.Type.Parse(value); err != nil {
+				problems = append(problems, errors.BadArguments{Msg: fmt.Sprintf("%s is not a valid %s for argument <%s>: %s", value, arg.Type, arg.Name, err), Argument: arg.Name})
+			}
+		}
 		parsed = append(parsed, *arg.provided...)
 	}
 
 	if len(parsed) != len(supplied) {
-		return errors.BadArguments{Msg: fmt.Sprintf("Unexpected arguments provided: %s", supplied)}
+		problems = append(problems, errors.BadArguments{Msg: fmt.Sprintf("Unexpected arguments provided: %s", supplied)})
 	}
-	return nil
+	return errors.Combine(problems...)
 }
 
+// AreValid returns an error listing every invalid argument.
 func (args *Arguments) AreValid() error {
+	problems := []error{}
 	for _, arg := range *args {
-		if err := arg.Validate(); err != nil {
-			return err
-		}
+		problems = append(problems, arg.Validate())
 	}
 
-	return nil
+	return errors.Combine(problems...)
 }
 
 // CompletionFunction is called by cobra when asked to complete arguments.
@@ -112,7 +118,9 @@ func (args *Arguments) CompletionFunction(cc *cobra.Command, provided []string,
 		argsCompleted := len(provided)
 		lastArg := (*args)[len(*args)-1]
 		hasVariadicArg := expectedArgLen > 0 && lastArg.Variadic
-		lastArg.Command.Options.Parse(cc.Flags())
+		if err := lastArg.Command.Options.Parse(cc.Flags()); err != nil {
+			return []string{err.Error()}, cobra.ShellCompDirectiveDefault
+		}
 		if err := args.Parse(provided); err != nil {
 			return []string{err.Error()}, cobra.ShellCompDirectiveDefault
 		}
@@ -166,12 +174,19 @@ type Argument struct {
 	Default any `json:"default,omitempty" yaml:"default,omitempty" validate:"excluded_with=Required"`
 	// Variadic makes an argument a list of all values from this one on.
 	Variadic bool `json:"variadic" yaml:"variadic"`
+	// Type represents the type of value expected to be provided for this argument.
+	Type ValueType `json:"type,omitempty" yaml:"type,omitempty" validate:"omitempty,oneof=string bool int float duration time url ip cidr bytesize map"`
 	// Required raises an error if an argument is not provided.
 	Required bool `json:"required" yaml:"required" validate:"excluded_with=Default"`
 	// Values describes autocompletion and validation for an argument
-	Values   *ValueSource `json:"values,omitempty" yaml:"values" validate:"omitempty"`
-	Command  *Command     `json:"-" yaml:"-" validate:"-"`
+	Values *ValueSource `json:"values,omitempty" yaml:"values" validate:"omitempty"`
+	// Validation checks user-supplied values, in addition to Values.
+	Validation *Validation `json:"validation,omitempty" yaml:"validation,omitempty" validate:"omitempty"`
+	// Secret values are masked when prompted for, and redacted when explaining an invocation.
+	Secret   bool     `json:"secret,omitempty" yaml:"secret,omitempty"`
+	Command  *Command `json:"-" yaml:"-" validate:"-"`
 	provided *[]string
+	source   Source
 }
 
 func (arg *Argument) EnvName() string {
@@ -180,6 +195,15 @@ func (arg *Argument) EnvName() string {
 
 func (arg *Argument) SetValue(value []string) {
 	arg.provided = &value
+	arg.source = Source{Kind: SourceOverride}
+}
+
+// Source tells where the resolved value for this argument came from.
+func (arg *Argument) Source() Source {
+	if arg.source.Kind == "" {
+		return Source{Kind: SourceDefault}
+	}
+	return arg.source
 }
 
 func (arg *Argument) IsKnown() bool {
@@ -187,7 +211,7 @@ func (arg *Argument) IsKnown() bool {
 }
 
 func (arg *Argument) ToString() string {
-	val := arg.ToValue()
+	val := arg.rawValue()
 
 	if arg.Variadic {
 		val := val.([]string)
@@ -197,7 +221,38 @@ func (arg *Argument) ToString() string {
 	return val.(string)
 }
 
+// ToValue returns the resolved value for this argument, as the go type for its Type.
 func (arg *Argument) ToValue() any {
+	value := arg.rawValue()
+	if arg.Type == ValueTypeDefault || arg.Type == ValueTypeString {
+		return value
+	}
+
+	if !arg.Variadic {
+		raw := value.(string)
+		if raw == "" {
+			return nil
+		}
+		typed, err := arg.Type.Parse(raw)
+		if err != nil {
+			arg.Command.log().Warnf("Could not parse value <%s> as %s for argument <%s>: %s", raw, arg.Type, arg.Name, err)
+			return nil
+		}
+		return typed
+	}
+
+	values := &typedFlag{kind: arg.Type, repeated: true}
+	for _, raw := range value.([]string) {
+		if err := values.Set(raw); err != nil {
+			arg.Command.log().Warnf("Could not parse value <%s> as %s for argument <%s>: %s", raw, arg.Type, arg.Name, err)
+			return nil
+		}
+	}
+	return values.value
+}
+
+// rawValue returns the resolved value for this argument, as a string or, if variadic, a slice of strings.
+func (arg *Argument) rawValue() any {
 	var value any
 	if arg.IsKnown() {
 		if arg.Variadic {
@@ -209,15 +264,9 @@ func (arg *Argument) ToValue() any {
 	} else {
 		if arg.Default != nil {
 			if arg.Variadic {
-				defaultSlice := anySliceToStringSlice(arg.Default)
-				// for _, valI := range arg.Default.([]any) {
-				// 	valStr := valI.(string)
-				// 	defaultSlice = append(defaultSlice, valStr)
-				// }
-
-				value = defaultSlice
+				value = formatEach(arg.Type, arg.Default)
 			} else {
-				value = arg.Default.(string)
+				value = arg.Type.Format(arg.Default)
 			}
 		} else {
 			if arg.Variadic {
@@ -234,12 +283,24 @@ func (arg *Argument) ToValue() any {
 func (arg *Argument) Validate() error {
 	if !arg.IsKnown() {
 		if arg.Required {
-			return errors.BadArguments{Msg: fmt.Sprintf("Missing argument for %s", strings.ToUpper(arg.Name))}
+			return errors.BadArguments{Msg: fmt.Sprintf("Missing argument for %s", strings.ToUpper(arg.Name)), Argument: arg.Name}
 		}
 
 		return nil
 	}
 
+	if arg.Source().Kind == SourceDefault {
+		return arg.validateValues()
+	}
+
+	return errors.Combine(
+		arg.validateValues(),
+		validateEach(arg.Validation, arg.Type, *arg.provided, fmt.Sprintf("argument <%s>", arg.Name), errors.BadArguments{Argument: arg.Name}),
+	)
+}
+
+// validateValues checks provided values appear in those resolved by the argument's value source.
+func (arg *Argument) validateValues() error {
 	if !arg.Validates() {
 		return nil
 	}
@@ -249,20 +310,19 @@ func (arg *Argument) Validate() error {
 		return err
 	}
 
+	values := []string{arg.ToString()}
 	if arg.Variadic {
-		for _, current := range *arg.provided {
-			if !contains(validValues, current) {
-				return errors.BadArguments{Msg: fmt.Sprintf("%s is not a valid value for argument <%s>. Valid options are: %s", current, arg.Name, strings.Join(validValues, ", "))}
-			}
-		}
-	} else {
-		current := arg.ToValue().(string)
+		values = *arg.provided
+	}
+
+	problems := []error{}
+	for _, current := range values {
 		if !contains(validValues, current) {
-			return errors.BadArguments{Msg: fmt.Sprintf("%s is not a valid value for argument <%s>. Valid options are: %s", current, arg.Name, strings.Join(validValues, ", "))}
+			problems = append(problems, errors.BadArguments{Msg: fmt.Sprintf("%s is not a valid value for argument <%s>. Valid options are: %s", current, arg.Name, strings.Join(validValues, ", ")), Argument: arg.Name})
 		}
 	}
 
-	return nil
+	return errors.Combine(problems...)
 }
 
 // Validates tells if the user-supplied value needs validation.
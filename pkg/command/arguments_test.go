@@ -92,6 +92,14 @@ func TestParse(t *testing.T) {
 	if !reflect.DeepEqual(val, expected) {
 		t.Fatalf("variadic argument does not match. expected: %s, got %s", expected, val)
 	}
+
+	if src := cmd.Arguments[0].Source(); src.String() != "argument #1" {
+		t.Fatalf("unexpected source for first argument: %s", src)
+	}
+
+	if src := cmd.Arguments[1].Source(); src.Kind != SourceDefault {
+		t.Fatalf("unexpected source for variadic argument: %s", src)
+	}
 }
 
 func TestBeforeParse(t *testing.T) {
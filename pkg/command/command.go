@@ -3,21 +3,36 @@
 package command
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"text/template"
 
+	"git.rob.mx/nidito/chinampa/pkg/config"
+	"git.rob.mx/nidito/chinampa/pkg/errors"
 	"git.rob.mx/nidito/chinampa/pkg/logger"
+	"git.rob.mx/nidito/chinampa/pkg/progress"
+	"git.rob.mx/nidito/chinampa/pkg/render"
 	"git.rob.mx/nidito/chinampa/pkg/runtime"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
 
-var log = logger.Sub("chinampa:command")
+// component names this package in log entries.
+const component = "chinampa:command"
 
 type HelpFunc func(printLinks bool) string
 type Action func(cmd *Command) error
 
+// ContextAction is an Action that receives a context, cancelled upon receiving
+// SIGINT or SIGTERM, or when the parent context passed to chinampa expires.
+type ContextAction func(ctx context.Context, cmd *Command) error
+
 type Command struct {
 	Path []string `json:"path" yaml:"path"`
 	// Summary is a short description of a command, on supported shells this is part of the autocomplete prompt
@@ -27,19 +42,120 @@ type Command struct {
 	// A list of arguments for a command
 	Arguments Arguments `json:"arguments" yaml:"arguments" validate:"dive"`
 	// A map of option names to option definitions
-	Options  Options  `json:"options" yaml:"options" validate:"dive"`
-	HelpFunc HelpFunc `json:"-" yaml:"-"`
+	Options Options `json:"options" yaml:"options" validate:"dive"`
+	// Constraints between options and arguments, enforced before running
+	Constraints Constraints `json:"constraints,omitempty" yaml:"constraints,omitempty"`
+	// Destructive commands ask users to confirm before running, unless --yes is given
+	Destructive *Confirmation `json:"destructive,omitempty" yaml:"destructive,omitempty"`
+	// ValidateInput checks parsed arguments and options together, after they've been validated individually
+	ValidateInput InputValidator `json:"-" yaml:"-"`
+	HelpFunc      HelpFunc       `json:"-" yaml:"-"`
 	// The action to take upon running
-	Action       Action `json:"-" yaml:"-"`
-	runtimeFlags *pflag.FlagSet
-	Cobra        *cobra.Command `json:"-" yaml:"-"`
+	Action Action `json:"-" yaml:"-"`
+	// ContextAction is taken upon running, instead of Action, when set
+	ContextAction ContextAction `json:"-" yaml:"-"`
+	// Middleware wraps this command's action, as well as those of its children when this is a group parent
+	Middleware          []Middleware `json:"-" yaml:"-"`
+	inheritedMiddleware []Middleware
+	runtimeFlags        *pflag.FlagSet
+	ctx                 context.Context
+	config              func() (config.Layers, error)
+	screen              *progress.Screen
+	helpTemplate        *template.Template
+	Cobra               *cobra.Command `json:"-" yaml:"-"`
 	// Meta stores application specific stuff
 	Meta   any  `json:"meta" yaml:"meta"`
 	Hidden bool `json:"-" yaml:"-"`
 }
 
 func (cmd *Command) IsRoot() bool {
-	return cmd.FullName() == runtime.Executable
+	return cmd.FullName() == cmd.Runtime().Executable
+}
+
+// HasAction tells if this command can be run, as opposed to only holding metadata for a group of commands.
+func (cmd *Command) HasAction() bool {
+	return cmd.Action != nil || cmd.ContextAction != nil
+}
+
+// Context returns the context for the current invocation of this command, or context.Background() before it runs.
+func (cmd *Command) Context() context.Context {
+	if cmd.ctx != nil {
+		return cmd.ctx
+	}
+
+	if cmd.Cobra != nil {
+		// commands only rendering help are bound to cobra, but not run
+		if ctx := cmd.Cobra.Root().Context(); ctx != nil {
+			return ctx
+		}
+	}
+	return context.Background()
+}
+
+// Runtime returns the settings of the current invocation of this command, or those of the program's own before it
+// runs, see runtime.FromContext.
+func (cmd *Command) Runtime() *runtime.Runtime {
+	return runtime.FromContext(cmd.Context())
+}
+
+// Logger returns the logger of the current invocation of this command, writing to its stderr, or logger.Main before
+// it runs, see logger.FromContext.
+func (cmd *Command) Logger() *logrus.Entry {
+	return logger.FromContext(cmd.Context())
+}
+
+// log returns the logger of the current invocation of cmd, if any, for entries of this package.
+func (cmd *Command) log() *logrus.Entry {
+	if cmd == nil {
+		return logger.SubFromContext(context.Background(), component)
+	}
+	return logger.SubFromContext(cmd.Context(), component)
+}
+
+// Stdin returns the input stream of the current invocation of this command, os.Stdin unless set by the program.
+func (cmd *Command) Stdin() io.Reader {
+	if cmd.Cobra == nil {
+		return os.Stdin
+	}
+	return cmd.Cobra.InOrStdin()
+}
+
+// Stdout returns the output stream of the current invocation of this command, os.Stdout unless set by the program.
+func (cmd *Command) Stdout() io.Writer {
+	if cmd.Cobra == nil {
+		return os.Stdout
+	}
+	return cmd.Cobra.OutOrStdout()
+}
+
+// Stderr returns the error stream of the current invocation of this command, os.Stderr unless set by the program.
+func (cmd *Command) Stderr() io.Writer {
+	if cmd.Cobra == nil {
+		return os.Stderr
+	}
+	return cmd.Cobra.ErrOrStderr()
+}
+
+// Output returns how the current invocation renders data, in the format requested by the user with --output and
+// --format, see render.OutputFor. Options of this command named like them are left alone.
+func (cmd *Command) Output() *render.Output {
+	return render.OutputFor(cmd.Runtime())
+}
+
+// Render writes data to the stdout of the current invocation, in the format requested by the user with --output and
+// --format, see Output.
+func (cmd *Command) Render(data any) error {
+	return cmd.Output().Render(cmd.Stdout(), data)
+}
+
+// Progress starts showing the progress of a task called label on the stderr of the current invocation, as a spinner
+// when total is zero or less, see progress.New. Bars still in progress once the action returns are marked done.
+func (cmd *Command) Progress(label string, total int) *progress.Bar {
+	if cmd.screen == nil {
+		// not running, so there's no invocation to draw bars for
+		return progress.New(cmd.Stderr(), label, total)
+	}
+	return cmd.screen.New(label, total)
 }
 
 func (cmd *Command) SetBindings() *Command {
@@ -60,6 +176,53 @@ func (cmd *Command) SetBindings() *Command {
 	return ptr
 }
 
+// Clone returns a copy of this command's definition, without the state of any invocation, that may be parsed and
+// run independently of cmd.
+func (cmd *Command) Clone() *Command {
+	clone := *cmd
+	clone.Path = append([]string{}, cmd.Path...)
+	clone.Middleware = append([]Middleware{}, cmd.Middleware...)
+	clone.inheritedMiddleware = append([]Middleware{}, cmd.inheritedMiddleware...)
+	clone.runtimeFlags = nil
+	clone.ctx = nil
+	clone.config = nil
+	clone.screen = nil
+	clone.Cobra = nil
+
+	if cmd.Arguments != nil {
+		clone.Arguments = make(Arguments, len(cmd.Arguments))
+		for idx, arg := range cmd.Arguments {
+			if arg == nil {
+				continue
+			}
+			argClone := *arg
+			argClone.provided = nil
+			argClone.source = Source{}
+			argClone.Values = arg.Values.clone()
+			argClone.Validation = arg.Validation.clone()
+			clone.Arguments[idx] = &argClone
+		}
+	}
+
+	if cmd.Options != nil {
+		clone.Options = make(Options, len(cmd.Options))
+		for name, opt := range cmd.Options {
+			if opt == nil {
+				clone.Options[name] = nil
+				continue
+			}
+			optClone := *opt
+			optClone.provided = nil
+			optClone.source = Source{}
+			optClone.Values = opt.Values.clone()
+			optClone.Validation = opt.Validation.clone()
+			clone.Options[name] = &optClone
+		}
+	}
+
+	return clone.SetBindings()
+}
+
 func (cmd *Command) Name() string {
 	return cmd.Path[len(cmd.Path)-1]
 }
@@ -74,6 +237,15 @@ func (cmd *Command) FlagSet() *pflag.FlagSet {
 		fs.SortFlags = false
 		fs.Usage = func() {}
 		for name, opt := range cmd.Options {
+			if opt.Repeated && (opt.Type == ValueTypeBoolean || opt.Type == ValueTypeInt) {
+				value, err := newTypedFlag(opt.Type, true, opt.Default)
+				if err != nil {
+					cmd.log().Warnf("Could not parse default with value <%v> as %s for option <%s>: %s", opt.Default, opt.Type, name, err)
+				}
+				fs.VarP(value, name, opt.ShortName, opt.Description)
+				continue
+			}
+
 			switch opt.Type {
 			case ValueTypeBoolean:
 				def := false
@@ -90,7 +262,7 @@ func (cmd *Command) FlagSet() *pflag.FlagSet {
 					case string:
 						casted, err := strconv.Atoi(val)
 						if err != nil {
-							log.Warnf("Could not parse default with value <%s> as integer for option <%s>", val, name)
+							cmd.log().Warnf("Could not parse default with value <%s> as integer for option <%s>", val, name)
 						}
 						def = casted
 					}
@@ -111,7 +283,7 @@ func (cmd *Command) FlagSet() *pflag.FlagSet {
 						case string:
 							def = []string{defV}
 						default:
-							logger.Errorf("Invalid default for repeated option %s configuration: %+v", name, defV)
+							cmd.log().Errorf("Invalid default for repeated option %s configuration: %+v", name, defV)
 						}
 					}
 
@@ -123,9 +295,18 @@ func (cmd *Command) FlagSet() *pflag.FlagSet {
 					}
 					fs.StringP(name, opt.ShortName, def, opt.Description)
 				}
+			case ValueTypeFloat, ValueTypeDuration, ValueTypeTime, ValueTypeURL, ValueTypeIP, ValueTypeCIDR, ValueTypeByteSize, ValueTypeMap, ValueTypeCount:
+				value, err := newTypedFlag(opt.Type, opt.Repeated && opt.Type != ValueTypeMap && opt.Type != ValueTypeCount, opt.Default)
+				if err != nil {
+					cmd.log().Warnf("Could not parse default with value <%v> as %s for option <%s>: %s", opt.Default, opt.Type, name, err)
+				}
+				flag := fs.VarPF(value, name, opt.ShortName, opt.Description)
+				if opt.Type == ValueTypeCount {
+					flag.NoOptDefVal = countIncrement
+				}
 			default:
 				// ignore flag
-				log.Warnf("Ignoring unknown option type <%s> for option <%s>", opt.Type, name)
+				cmd.log().Warnf("Ignoring unknown option type <%s> for option <%s>", opt.Type, name)
 				continue
 			}
 		}
@@ -135,37 +316,87 @@ func (cmd *Command) FlagSet() *pflag.FlagSet {
 	return cmd.runtimeFlags
 }
 
-func (cmd *Command) ParseInput(cc *cobra.Command, args []string) error {
-	if err := cmd.Arguments.Parse(args); err != nil {
-		return err
+// configPath returns the path to this command, without the executable name.
+func (cmd *Command) configPath() []string {
+	if len(cmd.Path) > 0 && cmd.Path[0] == cmd.Runtime().Executable {
+		return cmd.Path[1:]
 	}
+	return cmd.Path
+}
+
+// ParseInput resolves the values of arguments and options and, unless skipped, validates them, returning an
+// error listing every problem found. Missing required values are prompted for on terminals, whether validation is
+// skipped or not, see prompt.PrompterFunc.
+func (cmd *Command) ParseInput(cc *cobra.Command, args []string) error {
 	skipValidation, _ := cc.Flags().GetBool("skip-validation")
-	cmd.Options.Parse(cc.Flags())
-	if !skipValidation {
-		log.Debug("Validating arguments")
-		if err := cmd.Arguments.AreValid(); err != nil {
-			return err
+	configFile, _ := cc.Flags().GetString("config")
+	// config files are only read if an option is missing a value, so broken ones only fail commands that need them
+	cmd.config = sync.OnceValues(func() (config.Layers, error) {
+		layers, err := config.Load(cmd.Runtime().Executable, configFile)
+		for _, layer := range layers {
+			cmd.log().Debugf("Loaded config file %s", layer.Path)
 		}
+		return layers, err
+	})
 
-		log.Debug("Validating flags")
-		if err := cmd.Options.AreValid(); err != nil {
-			log.Debugf("Invalid flags for %s: %s", cmd.FullName(), err)
-			return err
-		}
+	if err := errors.Combine(cmd.Arguments.Parse(args), cmd.Options.Parse(cc.Flags())); err != nil {
+		return err
+	}
+
+	if err := cmd.promptMissing(); err != nil {
+		return err
+	}
+
+	if skipValidation || !cmd.Runtime().ValidationEnabled() {
+		return nil
+	}
+
+	cmd.log().Debug("Validating arguments, flags and constraints")
+	if err := errors.Combine(cmd.Arguments.AreValid(), cmd.Options.AreValid(), cmd.CheckConstraints()); err != nil {
+		cmd.log().Debugf("Invalid input for %s: %s", cmd.FullName(), err)
+		return err
+	}
+
+	if err := cmd.validateInput(); err != nil {
+		cmd.log().Debugf("Invalid input for %s: %s", cmd.FullName(), err)
+		return err
 	}
 
 	return nil
 }
 
+// Run parses input into a Clone of cmd, the invocation handed to middleware and the action, leaving cmd untouched.
 func (cmd *Command) Run(cc *cobra.Command, args []string) error {
-	log.Debugf("running command %s", cmd.FullName())
+	invocation := cmd.Clone()
+	invocation.Cobra = cc
+	invocation.ctx = cc.Context()
+	invocation.log().Debugf("running command %s", cmd.FullName())
+
+	// global flags are read as parsed from now on, so only those meant for the program count
+	rt := invocation.Runtime()
+	rt.Bind(cc.Flags(), cc.Root().PersistentFlags())
+	logger.Refresh(invocation.ctx, rt)
 
-	if err := cmd.ParseInput(cc, args); err != nil {
-		log.Debugf("Parsing input to command %s failed: %s", cmd.FullName(), err)
+	err := invocation.ParseInput(cc, args)
+	// explaining invalid input helps the most, so do it before giving up
+	invocation.explain(cc)
+	if err != nil {
+		invocation.log().Debugf("Parsing input to command %s failed: %s", cmd.FullName(), err)
 		return err
 	}
 
-	return cmd.Action(cmd)
+	if err := invocation.confirm(); err != nil {
+		return err
+	}
+
+	invocation.screen = progress.NewScreen(invocation.Stderr(), invocation.Logger(), rt)
+	defer invocation.screen.Finish()
+	return invocation.runMiddleware(func() error {
+		if invocation.ContextAction != nil {
+			return invocation.ContextAction(invocation.Context(), invocation)
+		}
+		return invocation.Action(invocation)
+	})
 }
 
 func (cmd *Command) SetCobra(cc *cobra.Command) {
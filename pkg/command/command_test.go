@@ -0,0 +1,134 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package command_test
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+
+	. "git.rob.mx/nidito/chinampa/pkg/command"
+	"github.com/spf13/cobra"
+)
+
+type ctxKey string
+
+func TestRunContextAction(t *testing.T) {
+	parent, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey("test"), "value"))
+	cancel()
+
+	called := false
+	cmd := (&Command{
+		Path: []string{"test", "context"},
+		ContextAction: func(ctx context.Context, cmd *Command) error {
+			called = true
+			if ctx.Value(ctxKey("test")) != "value" {
+				t.Fatalf("action did not receive context")
+			}
+			if ctx.Err() != context.Canceled {
+				t.Fatalf("action received uncancelled context: %v", ctx.Err())
+			}
+			if cmd.Context() != ctx {
+				t.Fatalf("command context differs from action's")
+			}
+			return nil
+		},
+	}).SetBindings()
+
+	if !cmd.HasAction() {
+		t.Fatalf("command with context action has no action")
+	}
+
+	cc := &cobra.Command{Use: "context"}
+	cc.Flags().AddFlagSet(cmd.FlagSet())
+	cc.SetContext(parent)
+	if err := cmd.Run(cc, []string{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !called {
+		t.Fatalf("context action was not called")
+	}
+}
+
+func TestValueSourceFuncContext(t *testing.T) {
+	var received context.Context
+	cmd := (&Command{
+		Path: []string{"test", "value-context"},
+		Arguments: Arguments{
+			{
+				Name: "first",
+				Values: &ValueSource{
+					Func: func(cmd *Command, currentValue, config string) ([]string, cobra.ShellCompDirective, error) {
+						received = cmd.Context()
+						return []string{"a"}, cobra.ShellCompDirectiveDefault, nil
+					},
+				},
+			},
+		},
+		Action: func(cmd *Command) error { return nil },
+	}).SetBindings()
+
+	parent := context.WithValue(context.Background(), ctxKey("test"), "value")
+	cc := &cobra.Command{Use: "value-context"}
+	cc.Flags().AddFlagSet(cmd.FlagSet())
+	cc.SetContext(parent)
+	if err := cmd.Run(cc, []string{"a"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if received == nil || received.Value(ctxKey("test")) != "value" {
+		t.Fatalf("value source func did not receive invocation context")
+	}
+}
+
+func TestRunIsReentrant(t *testing.T) {
+	cmd := (&Command{
+		Path: []string{"test", "reentrant"},
+		Arguments: Arguments{
+			{Name: "first", Values: &ValueSource{Static: &[]string{"a", "b", "c", "d"}}},
+		},
+		Options: Options{
+			"second": {Type: ValueTypeInt, Default: 1},
+		},
+		Action: func(cmd *Command) error {
+			first := cmd.Arguments[0].ToValue().(string)
+			second := cmd.Options["second"].ToValue().(int)
+			if expected := int(first[0]-'a') + 1; second != expected {
+				return fmt.Errorf("invocation of %s got --second %d, expected %d", first, second, expected)
+			}
+			return nil
+		},
+	}).SetBindings()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			first := string(rune('a' + i%4))
+			cc := &cobra.Command{Use: "reentrant"}
+			cc.Flags().AddFlagSet(cmd.Clone().FlagSet())
+			if err := cc.Flags().Parse([]string{"--second", strconv.Itoa(i%4 + 1)}); err != nil {
+				errs <- err
+				return
+			}
+			errs <- cmd.Run(cc, []string{first})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if cmd.Arguments[0].IsKnown() || cmd.Options["second"].IsKnown() {
+		t.Fatalf("running modified the command definition")
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package command
+
+import (
+	stderrors "errors"
+	"fmt"
+	"strings"
+
+	"git.rob.mx/nidito/chinampa/pkg/errors"
+	"git.rob.mx/nidito/chinampa/pkg/prompt"
+	"git.rob.mx/nidito/chinampa/pkg/statuscode"
+)
+
+// ErrNotConfirmed is wrapped by the errors returned when running a destructive command is declined, or could not be
+// confirmed because input is disabled or stdin is not a terminal.
+var ErrNotConfirmed = stderrors.New("not confirmed")
+
+// Confirmation describes how users confirm running a destructive command.
+type Confirmation struct {
+	// Message asks users to confirm, "Are you sure you want to run <command>?" by default
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+	// Argument names the argument whose value users must type to confirm, instead of answering yes
+	Argument string `json:"argument,omitempty" yaml:"argument,omitempty"`
+}
+
+// confirm asks users to confirm running a destructive command, unless --yes was given.
+func (cmd *Command) confirm() error {
+	rt := cmd.Runtime()
+	if cmd.Destructive == nil || rt.AssumeYes() {
+		return nil
+	}
+
+	message := cmd.Destructive.Message
+	if message == "" {
+		message = fmt.Sprintf("Are you sure you want to run %s?", cmd.FullName())
+	}
+
+	expected := ""
+	if name := cmd.Destructive.Argument; name != "" {
+		arg := cmd.Arguments.Get(name)
+		if arg == nil {
+			return errors.ExitError{Code: statuscode.ProgrammerError, Err: fmt.Errorf("%s is confirmed with unknown argument <%s>", cmd.FullName(), name)}
+		}
+		expected = arg.ToString()
+	}
+
+	var prompter prompt.Prompter
+	if rt.InputEnabled() {
+		prompter = prompt.PrompterFunc(cmd.Stdin(), cmd.Stderr())
+	}
+
+	if prompter == nil {
+		return errors.ExitError{
+			Code: statuscode.Usage,
+			Err:  fmt.Errorf("%w: %s is destructive and can only be confirmed on a terminal", ErrNotConfirmed, cmd.FullName()),
+			Hint: fmt.Sprintf("Pass --yes, or set %s=1, to run it without confirmation", rt.Env.AssumeYes),
+		}
+	}
+
+	confirmed, err := askConfirmation(prompter, message, expected)
+	if err != nil {
+		return promptError(err)
+	}
+
+	if !confirmed {
+		return errors.ExitError{Code: statuscode.Cancelled, Err: fmt.Errorf("%w: declined running %s", ErrNotConfirmed, cmd.FullName())}
+	}
+	return nil
+}
+
+// askConfirmation asks for yes or no, or for expected to be typed when not empty, telling if users confirmed.
+func askConfirmation(prompter prompt.Prompter, message, expected string) (bool, error) {
+	if expected != "" {
+		answer, err := prompter.Input(fmt.Sprintf("%s Type %q to confirm", message, expected), nil)
+		return answer == expected, err
+	}
+
+	answer, err := prompter.Input(message+" [y/N]", func(value string) error {
+		switch strings.ToLower(strings.TrimSpace(value)) {
+		case "", "y", "yes", "n", "no":
+			return nil
+		}
+		return fmt.Errorf("answer yes or no")
+	})
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, err
+	}
+	return false, err
+}
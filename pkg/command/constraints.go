@@ -0,0 +1,226 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"git.rob.mx/nidito/chinampa/pkg/errors"
+)
+
+// Constraints declare relationships between the options and arguments of a command. Names refer to options
+// and, when no option by that name exists, to arguments.
+//
+//	Constraints: command.Constraints{
+//		// exactly one of --file or --url
+//		MutuallyExclusive: [][]string{{"file", "url"}},
+//		OneRequired:       [][]string{{"file", "url"}},
+//		Requires:          map[string][]string{"cert": {"key"}},
+//		Conflicts:         map[string][]string{"all": {"ids"}},
+//	}
+type Constraints struct {
+	// MutuallyExclusive lists groups of which no more than one may be provided
+	MutuallyExclusive [][]string `json:"mutually-exclusive,omitempty" yaml:"mutually-exclusive,omitempty"` // nolint:tagliatelle
+	// OneRequired lists groups of which at least one must be provided
+	OneRequired [][]string `json:"one-required,omitempty" yaml:"one-required,omitempty"` // nolint:tagliatelle
+	// Requires maps names to those that must be provided alongside them
+	Requires map[string][]string `json:"requires,omitempty" yaml:"requires,omitempty"`
+	// Conflicts maps names to those that cannot be provided alongside them
+	Conflicts map[string][]string `json:"conflicts,omitempty" yaml:"conflicts,omitempty"`
+}
+
+// names returns every name referenced by these constraints.
+func (cs *Constraints) names() []string {
+	names := []string{}
+	for _, group := range append(append([][]string{}, cs.MutuallyExclusive...), cs.OneRequired...) {
+		names = append(names, group...)
+	}
+	for _, pairs := range []map[string][]string{cs.Requires, cs.Conflicts} {
+		for name, others := range pairs {
+			names = append(names, name)
+			names = append(names, others...)
+		}
+	}
+	return names
+}
+
+// isProvided tells if name was supplied by the user, as opposed to resolved from defaults.
+func (cmd *Command) isProvided(name string) bool {
+	if opt, ok := cmd.Options[name]; ok && opt != nil {
+		return opt.Source().Kind != SourceDefault
+	}
+
+	if arg := cmd.Arguments.Get(name); arg != nil {
+		return arg.IsKnown() && arg.Source().Kind != SourceDefault
+	}
+
+	return false
+}
+
+// displayName returns the representation of name in usage and errors, i.e. --option or ARGUMENT.
+func (cmd *Command) displayName(name string) string {
+	if _, ok := cmd.Options[name]; ok {
+		return "--" + name
+	}
+
+	if arg := cmd.Arguments.Get(name); arg != nil {
+		return arg.EnvName()
+	}
+
+	return name
+}
+
+// badInput returns errors.BadArguments with msg, naming name as the offending option or argument.
+func (cmd *Command) badInput(name, msg string) errors.BadArguments {
+	if _, ok := cmd.Options[name]; ok {
+		return errors.BadArguments{Msg: msg, Option: name}
+	}
+	return errors.BadArguments{Msg: msg, Argument: name}
+}
+
+// displayNames returns names as a list joined by conjunction, with each wrapped by quote.
+func (cmd *Command) displayNames(names []string, conjunction, quote string) string {
+	display := make([]string, 0, len(names))
+	for _, name := range names {
+		display = append(display, quote+cmd.displayName(name)+quote)
+	}
+
+	if len(display) < 2 {
+		return strings.Join(display, "")
+	}
+	return strings.Join(display[0:len(display)-1], ", ") + " " + conjunction + " " + display[len(display)-1]
+}
+
+func sortedKeys(pairs map[string][]string) []string {
+	keys := make([]string, 0, len(pairs))
+	for key := range pairs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// CheckConstraints returns an error listing missing required options and every unsatisfied Constraint.
+func (cmd *Command) CheckConstraints() error {
+	problems := []error{}
+	for _, name := range cmd.Options.names() {
+		if opt := cmd.Options[name]; opt != nil && opt.Required && !cmd.isProvided(name) {
+			problems = append(problems, errors.BadArguments{Msg: fmt.Sprintf("Missing required option %s", cmd.displayName(name)), Option: name})
+		}
+	}
+
+	cs := cmd.Constraints
+	for _, group := range cs.MutuallyExclusive {
+		provided := []string{}
+		for _, name := range group {
+			if cmd.isProvided(name) {
+				provided = append(provided, name)
+			}
+		}
+
+		if len(provided) > 1 {
+			problems = append(problems, errors.BadArguments{Msg: fmt.Sprintf("Only one of %s may be provided, got %s", cmd.displayNames(group, "or", ""), cmd.displayNames(provided, "and", ""))})
+		}
+	}
+
+	for _, group := range cs.OneRequired {
+		found := false
+		for _, name := range group {
+			if cmd.isProvided(name) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			problems = append(problems, errors.BadArguments{Msg: fmt.Sprintf("One of %s is required", cmd.displayNames(group, "or", ""))})
+		}
+	}
+
+	for _, name := range sortedKeys(cs.Requires) {
+		if !cmd.isProvided(name) {
+			continue
+		}
+
+		for _, other := range cs.Requires[name] {
+			if !cmd.isProvided(other) {
+				problems = append(problems, cmd.badInput(name, fmt.Sprintf("%s requires %s", cmd.displayName(name), cmd.displayName(other))))
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(cs.Conflicts) {
+		if !cmd.isProvided(name) {
+			continue
+		}
+
+		for _, other := range cs.Conflicts[name] {
+			if cmd.isProvided(other) {
+				problems = append(problems, cmd.badInput(name, fmt.Sprintf("%s cannot be used with %s", cmd.displayName(name), cmd.displayName(other))))
+			}
+		}
+	}
+
+	return errors.Combine(problems...)
+}
+
+// OptionsUsage returns the usage of required options and option groups, i.e. `--name NAME (--file FILE | --url URL)`.
+func (cmd *Command) OptionsUsage() []string {
+	usage := []string{}
+	for _, name := range cmd.Options.names() {
+		if opt := cmd.Options[name]; opt != nil && opt.Required {
+			usage = append(usage, opt.ToDesc(name))
+		}
+	}
+
+	oneRequired := map[string]bool{}
+	for _, group := range cmd.Constraints.OneRequired {
+		oneRequired[strings.Join(group, " ")] = true
+		usage = append(usage, "("+cmd.groupUsage(group)+")")
+	}
+
+	for _, group := range cmd.Constraints.MutuallyExclusive {
+		if !oneRequired[strings.Join(group, " ")] {
+			usage = append(usage, "["+cmd.groupUsage(group)+"]")
+		}
+	}
+
+	return usage
+}
+
+func (cmd *Command) groupUsage(group []string) string {
+	parts := make([]string, 0, len(group))
+	for _, name := range group {
+		if opt, ok := cmd.Options[name]; ok && opt != nil {
+			parts = append(parts, opt.ToDesc(name))
+		} else {
+			parts = append(parts, cmd.displayName(name))
+		}
+	}
+	return strings.Join(parts, " | ")
+}
+
+// ConstraintsHelp returns a description of each of the command's Constraints, for help and docs.
+func (cmd *Command) ConstraintsHelp() []string {
+	quoted := func(names []string, conjunction string) string {
+		return cmd.displayNames(names, conjunction, "`")
+	}
+
+	cs := cmd.Constraints
+	help := []string{}
+	for _, group := range cs.MutuallyExclusive {
+		help = append(help, fmt.Sprintf("Only one of %s may be provided", quoted(group, "or")))
+	}
+	for _, group := range cs.OneRequired {
+		help = append(help, fmt.Sprintf("One of %s is required", quoted(group, "or")))
+	}
+	for _, name := range sortedKeys(cs.Requires) {
+		help = append(help, fmt.Sprintf("%s requires %s", quoted([]string{name}, ""), quoted(cs.Requires[name], "and")))
+	}
+	for _, name := range sortedKeys(cs.Conflicts) {
+		help = append(help, fmt.Sprintf("%s cannot be used with %s", quoted([]string{name}, ""), quoted(cs.Conflicts[name], "or")))
+	}
+	return help
+}
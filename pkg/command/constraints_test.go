@@ -0,0 +1,136 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package command_test
+
+import (
+	"reflect"
+	"testing"
+
+	. "git.rob.mx/nidito/chinampa/pkg/command"
+	"github.com/spf13/cobra"
+)
+
+func constrainedCommand() *Command {
+	return (&Command{
+		Path:        []string{"test", "constraints"},
+		Summary:     "constrained",
+		Description: "constrained",
+		Arguments: Arguments{
+			{Name: "ids", Description: "ids", Variadic: true},
+		},
+		Options: Options{
+			"name": {Description: "name", Required: true},
+			"file": {Description: "file"},
+			"url":  {Description: "url"},
+			"cert": {Description: "cert"},
+			"key":  {Description: "key"},
+			"all":  {Type: ValueTypeBoolean, Description: "all"},
+		},
+		Constraints: Constraints{
+			MutuallyExclusive: [][]string{{"file", "url"}},
+			OneRequired:       [][]string{{"file", "url"}},
+			Requires:          map[string][]string{"cert": {"key"}},
+			Conflicts:         map[string][]string{"all": {"ids"}},
+		},
+	}).SetBindings()
+}
+
+func TestCheckConstraints(t *testing.T) {
+	cases := []struct {
+		Name  string
+		Args  []string
+		Error string
+	}{
+		{
+			Name: "satisfied",
+			Args: []string{"--name", "n", "--file", "f", "--cert", "c", "--key", "k", "a", "b"},
+		},
+		{
+			Name:  "required",
+			Args:  []string{"--file", "f"},
+			Error: "Missing required option --name",
+		},
+		{
+			Name:  "mutually exclusive",
+			Args:  []string{"--name", "n", "--file", "f", "--url", "u"},
+			Error: "Only one of --file or --url may be provided, got --file and --url",
+		},
+		{
+			Name:  "one required",
+			Args:  []string{"--name", "n"},
+			Error: "One of --file or --url is required",
+		},
+		{
+			Name:  "requires",
+			Args:  []string{"--name", "n", "--url", "u", "--cert", "c"},
+			Error: "--cert requires --key",
+		},
+		{
+			Name: "every problem",
+			Args: []string{"--file", "f", "--url", "u", "--cert", "c", "--all", "a"},
+			Error: `Found 4 problems:
+  - Missing required option --name
+  - Only one of --file or --url may be provided, got --file and --url
+  - --cert requires --key
+  - --all cannot be used with IDS`,
+		},
+		{
+			Name:  "conflicts",
+			Args:  []string{"--name", "n", "--url", "u", "--all", "a"},
+			Error: "--all cannot be used with IDS",
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			cmd := constrainedCommand()
+			cc := &cobra.Command{Use: "constraints"}
+			cc.Flags().AddFlagSet(cmd.FlagSet())
+			if err := cc.Flags().Parse(c.Args); err != nil {
+				t.Fatal(err)
+			}
+
+			err := cmd.ParseInput(cc, cc.Flags().Args())
+			if c.Error == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				return
+			}
+
+			if err == nil || err.Error() != c.Error {
+				t.Fatalf("expected error <%s>, got <%v>", c.Error, err)
+			}
+		})
+	}
+}
+
+func TestConstraintsUsage(t *testing.T) {
+	cmd := constrainedCommand()
+	cmd.Constraints.MutuallyExclusive = append(cmd.Constraints.MutuallyExclusive, []string{"all", "cert"})
+
+	usage := cmd.OptionsUsage()
+	expected := []string{"--name NAME", "(--file FILE | --url URL)", "[--all | --cert CERT]"}
+	if !reflect.DeepEqual(usage, expected) {
+		t.Fatalf("unexpected usage:\ngot   : %#v\nwanted: %#v", usage, expected)
+	}
+
+	help := cmd.ConstraintsHelp()
+	expected = []string{
+		"Only one of `--file` or `--url` may be provided",
+		"Only one of `--all` or `--cert` may be provided",
+		"One of `--file` or `--url` is required",
+		"`--cert` requires `--key`",
+		"`--all` cannot be used with `IDS`",
+	}
+	if !reflect.DeepEqual(help, expected) {
+		t.Fatalf("unexpected help:\ngot   : %#v\nwanted: %#v", help, expected)
+	}
+
+	cmd.Constraints.Requires["cert"] = []string{"unknown"}
+	report := cmd.Validate()
+	if report["constraints reference unknown option or argument <unknown>"] != 1 {
+		t.Fatalf("unknown constraint not reported: %v", report)
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package command
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"git.rob.mx/nidito/chinampa/pkg/errors"
+)
+
+const (
+	// TagArgument names the argument a struct field is decoded from.
+	TagArgument = "arg"
+	// TagOption names the option a struct field is decoded from.
+	TagOption = "option"
+)
+
+// Decode sets the fields of the struct pointed to by target to the resolved values of arguments and options,
+// as named by their `arg:"name"` or `option:"name"` tags. Empty names default to the kebab-cased field name.
+//
+//	var input struct {
+//		Name    string   `arg:"name"`
+//		Files   []string `arg:"files"`
+//		Retries int      `option:"retries"`
+//	}
+//	if err := cmd.Decode(&input); err != nil {
+//		return err
+//	}
+//
+// Fields of integer options not provided by the user and without a Default are left untouched, instead of getting
+// -1, the placeholder value of their flags.
+func (cmd *Command) Decode(target any) error {
+	ptr := reflect.ValueOf(target)
+	if ptr.Kind() != reflect.Pointer || ptr.IsNil() || ptr.Elem().Kind() != reflect.Struct {
+		return errors.BadArguments{Msg: fmt.Sprintf("cannot decode into %T, a pointer to a struct is required", target)}
+	}
+
+	opts := map[string]any{}
+	for name, opt := range cmd.Options {
+		if opt.Type == ValueTypeInt && opt.Source().Kind == SourceDefault && opt.Default == nil {
+			opts[name] = nil
+			continue
+		}
+		opts[name] = opt.ToValue()
+	}
+
+	return decodeStruct(ptr.Elem(), cmd.Arguments.AllKnown(), opts)
+}
+
+func decodeStruct(dst reflect.Value, args, opts map[string]any) error {
+	for idx := 0; idx < dst.NumField(); idx++ {
+		field := dst.Type().Field(idx)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := decodeStruct(dst.Field(idx), args, opts); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		kind := "argument"
+		known := args
+		name, ok := field.Tag.Lookup(TagArgument)
+		if !ok {
+			kind = "option"
+			known = opts
+			if name, ok = field.Tag.Lookup(TagOption); !ok {
+				continue
+			}
+		}
+
+		if name == "" {
+			name = kebabCase(field.Name)
+		}
+
+		value, exists := known[name]
+		if !exists {
+			return errors.BadArguments{Msg: fmt.Sprintf("unknown %s <%s> for field %s", kind, name, field.Name)}
+		}
+
+		if err := assign(dst.Field(idx), value); err != nil {
+			return errors.BadArguments{Msg: fmt.Sprintf("cannot decode %s <%s> into field %s: %s", kind, name, field.Name, err)}
+		}
+	}
+
+	return nil
+}
+
+// assign sets dst to value, converting between compatible types.
+func assign(dst reflect.Value, value any) error {
+	if value == nil {
+		return nil
+	}
+
+	src := reflect.ValueOf(value)
+	if src.Type().AssignableTo(dst.Type()) {
+		dst.Set(src)
+		return nil
+	}
+
+	str, isString := value.(string)
+	if vt := typeOf(dst.Type()); isString && vt.IsTyped() {
+		typed, err := vt.Parse(str)
+		if err != nil {
+			return err
+		}
+		return assign(dst, typed)
+	}
+
+	if src.Kind() == reflect.Pointer && !src.IsNil() && src.Elem().Type().AssignableTo(dst.Type()) {
+		dst.Set(src.Elem())
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.Pointer:
+		elem := reflect.New(dst.Type().Elem())
+		if err := assign(elem.Elem(), value); err != nil {
+			return err
+		}
+		dst.Set(elem)
+		return nil
+	case reflect.Slice:
+		if src.Kind() != reflect.Slice {
+			src = reflect.ValueOf([]any{value})
+		}
+		res := reflect.MakeSlice(dst.Type(), src.Len(), src.Len())
+		for idx := 0; idx < src.Len(); idx++ {
+			if err := assign(res.Index(idx), src.Index(idx).Interface()); err != nil {
+				return err
+			}
+		}
+		dst.Set(res)
+		return nil
+	case reflect.String:
+		if src.Kind() == reflect.String {
+			dst.SetString(src.String())
+			return nil
+		}
+	case reflect.Bool:
+		if isString {
+			parsed, err := strconv.ParseBool(str)
+			if err != nil {
+				return err
+			}
+			dst.SetBool(parsed)
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if isString {
+			parsed, err := strconv.ParseInt(str, 10, dst.Type().Bits())
+			if err != nil {
+				return err
+			}
+			dst.SetInt(parsed)
+			return nil
+		}
+		if src.CanInt() && !dst.OverflowInt(src.Int()) {
+			dst.SetInt(src.Int())
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if isString {
+			parsed, err := strconv.ParseUint(str, 10, dst.Type().Bits())
+			if err != nil {
+				return err
+			}
+			dst.SetUint(parsed)
+			return nil
+		}
+		if src.CanInt() && src.Int() >= 0 && !dst.OverflowUint(uint64(src.Int())) {
+			dst.SetUint(uint64(src.Int()))
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if isString {
+			parsed, err := strconv.ParseFloat(str, dst.Type().Bits())
+			if err != nil {
+				return err
+			}
+			dst.SetFloat(parsed)
+			return nil
+		}
+		if src.CanInt() {
+			dst.SetFloat(float64(src.Int()))
+			return nil
+		}
+		if src.CanFloat() {
+			dst.SetFloat(src.Float())
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%T is not compatible with %s", value, dst.Type())
+}
@@ -0,0 +1,180 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package command_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	. "git.rob.mx/nidito/chinampa/pkg/command"
+	"git.rob.mx/nidito/chinampa/pkg/errors"
+)
+
+func decodeCommand(t *testing.T, args []string, flags ...string) *Command {
+	t.Helper()
+	cmd := (&Command{
+		Path: []string{"test", "decode"},
+		Arguments: Arguments{
+			{Name: "first"},
+			{Name: "count", Default: "3"},
+			{Name: "rest", Variadic: true},
+		},
+		Options: Options{
+			"name":  {Default: "default"},
+			"bool":  {Type: ValueTypeBoolean},
+			"int":   {Type: ValueTypeInt, Default: 2},
+			"limit": {Type: ValueTypeInt},
+			"items": {Repeated: true},
+		},
+	}).SetBindings()
+
+	fs := cmd.FlagSet()
+	if err := fs.Parse(flags); err != nil {
+		t.Fatalf("could not parse flags: %s", err)
+	}
+	if err := cmd.Arguments.Parse(args); err != nil {
+		t.Fatalf("could not parse arguments: %s", err)
+	}
+	cmd.Options.Parse(fs)
+	return cmd
+}
+
+func TestDecode(t *testing.T) {
+	type embedded struct {
+		Items []string `option:"items"`
+	}
+	type input struct {
+		embedded
+		First   string   `arg:"first"`
+		Count   uint8    `arg:"count"`
+		Rest    []string `arg:"rest"`
+		Name    *string  `option:"name"`
+		Bool    bool     `option:"bool"`
+		Int     int64    `option:"int"`
+		Float   float64  `option:"int"`
+		Ignored string
+	}
+
+	cmd := decodeCommand(t, []string{"a", "12", "b", "c"}, "--bool", "--items", "x", "--items", "y")
+	res := input{Ignored: "untouched"}
+	if err := cmd.Decode(&res); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	name := "default"
+	expected := input{
+		embedded: embedded{Items: []string{"x", "y"}},
+		First:    "a",
+		Count:    12,
+		Rest:     []string{"b", "c"},
+		Name:     &name,
+		Bool:     true,
+		Int:      2,
+		Float:    2,
+		Ignored:  "untouched",
+	}
+	if !reflect.DeepEqual(res, expected) {
+		t.Fatalf("unexpected result:\ngot   : %+v\nwanted: %+v", res, expected)
+	}
+}
+
+func TestDecodeUnsetIntegers(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Flags    []string
+		Expected int
+	}{
+		{Name: "unset", Expected: 0},
+		{Name: "zero", Flags: []string{"--limit", "0"}, Expected: 0},
+		{Name: "placeholder given", Flags: []string{"--limit=-1"}, Expected: -1},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			cmd := decodeCommand(t, []string{"a"}, c.Flags...)
+			res := struct {
+				Limit int `option:"limit"`
+			}{}
+			if err := cmd.Decode(&res); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if res.Limit != c.Expected {
+				t.Fatalf("unexpected limit %d, expected %d", res.Limit, c.Expected)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	cases := []struct {
+		Name   string
+		Target any
+		Args   []string
+		Error  string
+	}{
+		{
+			Name:   "non-pointer",
+			Target: struct{}{},
+			Error:  "cannot decode into struct {}",
+		},
+		{
+			Name: "unknown argument",
+			Target: &struct {
+				Missing string `arg:"missing"`
+			}{},
+			Error: "unknown argument <missing> for field Missing",
+		},
+		{
+			Name: "unknown option",
+			Target: &struct {
+				Missing string `option:"missing"`
+			}{},
+			Error: "unknown option <missing> for field Missing",
+		},
+		{
+			Name: "bad integer",
+			Target: &struct {
+				First int `arg:"first"`
+			}{},
+			Args:  []string{"not-a-number"},
+			Error: "cannot decode argument <first> into field First",
+		},
+		{
+			Name: "overflow",
+			Target: &struct {
+				Count int8 `arg:"count"`
+			}{},
+			Args:  []string{"a", "1000"},
+			Error: "cannot decode argument <count> into field Count",
+		},
+		{
+			Name: "incompatible",
+			Target: &struct {
+				Bool string `option:"bool"`
+			}{},
+			Error: "cannot decode option <bool> into field Bool: bool is not compatible with string",
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			cmd := decodeCommand(t, c.Args)
+			err := cmd.Decode(c.Target)
+			if err == nil {
+				t.Fatalf("expected error, got none")
+			}
+
+			if _, ok := err.(errors.BadArguments); !ok {
+				t.Fatalf("expected BadArguments, got %T", err)
+			}
+
+			if !strings.HasPrefix(err.Error(), c.Error) {
+				t.Fatalf("could not find error <%s> got <%s>", c.Error, err)
+			}
+		})
+	}
+}
@@ -4,9 +4,9 @@ package command
 
 import (
 	"bytes"
+	"text/template"
 
 	"git.rob.mx/nidito/chinampa/pkg/render"
-	"git.rob.mx/nidito/chinampa/pkg/runtime"
 	"github.com/spf13/cobra"
 )
 
@@ -17,6 +17,12 @@ type combinedCommand struct {
 	HTMLOutput    bool
 }
 
+// SetHelpTemplate sets the template used to render help for this command, render.TemplateCommandHelp, if set, or
+// render.NewHelpTemplate by default.
+func (cmd *Command) SetHelpTemplate(tpl *template.Template) {
+	cmd.helpTemplate = tpl
+}
+
 func (cmd *Command) HasAdditionalHelp() bool {
 	return cmd.HelpFunc != nil
 }
@@ -38,7 +44,7 @@ func (cmd *Command) HelpRenderer(globalOptions Options) func(cc *cobra.Command,
 		if err != nil {
 			panic(err)
 		}
-		_, err = cc.OutOrStderr().Write(content)
+		_, err = cc.ErrOrStderr().Write(content)
 		if err != nil {
 			panic(err)
 		}
@@ -47,19 +53,26 @@ func (cmd *Command) HelpRenderer(globalOptions Options) func(cc *cobra.Command,
 
 func (cmd *Command) ShowHelp(globalOptions Options, _ []string) ([]byte, error) {
 	var buf bytes.Buffer
-	colorEnabled := runtime.ColorEnabled()
+	rt := cmd.Runtime()
 	c := &combinedCommand{
 		Spec:          cmd,
 		Command:       cmd.Cobra,
 		GlobalOptions: globalOptions,
-		HTMLOutput:    runtime.HelpStyle() == "markdown",
+		HTMLOutput:    rt.HelpStyle() == "markdown",
+	}
+	tpl := cmd.helpTemplate
+	if tpl == nil {
+		tpl = render.TemplateCommandHelp
+	}
+	if tpl == nil {
+		tpl = render.NewHelpTemplate(rt.Executable)
 	}
-	err := render.HelpTemplate(runtime.Executable).Execute(&buf, c)
+	err := tpl.Execute(&buf, c)
 	if err != nil {
 		return nil, err
 	}
 
-	content, err := render.Markdown(buf.Bytes(), colorEnabled)
+	content, err := render.MarkdownStyled(buf.Bytes(), rt.HelpStyle(), rt.ColorEnabled())
 	if err != nil {
 		return nil, err
 	}
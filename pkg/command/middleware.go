@@ -0,0 +1,63 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package command
+
+import (
+	"context"
+	stderrors "errors"
+)
+
+// ErrSkipAction may be returned, or wrapped, by a Middleware's Before hook to prevent the action from running,
+// without failing.
+var ErrSkipAction = stderrors.New("skip action")
+
+// Middleware wraps the running of a command's action with hooks.
+type Middleware struct {
+	// Name identifies this middleware in logs.
+	Name string
+	// Before runs after input has been parsed and validated, and may modify it. Returning an error stops other Before hooks and the action from running.
+	Before func(cmd *Command) error
+	// After runs once the action returns, or a Before hook stops the chain, with the resulting error. It returns the error to report back.
+	After func(cmd *Command, err error) error
+}
+
+// InheritMiddleware sets the chain of middleware that runs before this command's own.
+func (cmd *Command) InheritMiddleware(chain ...Middleware) {
+	cmd.inheritedMiddleware = chain
+}
+
+// SetContext replaces the context for the current invocation, so middleware can pass values to actions.
+func (cmd *Command) SetContext(ctx context.Context) {
+	cmd.ctx = ctx
+}
+
+// runMiddleware calls action wrapped by the inherited and own middleware of cmd.
+func (cmd *Command) runMiddleware(action func() error) (err error) {
+	chain := append(append([]Middleware{}, cmd.inheritedMiddleware...), cmd.Middleware...)
+	ran := 0
+	for _, mw := range chain {
+		if mw.Before != nil {
+			cmd.log().Tracef("running middleware %s before %s", mw.Name, cmd.FullName())
+			if err = mw.Before(cmd); err != nil {
+				cmd.log().Debugf("middleware %s stopped %s: %s", mw.Name, cmd.FullName(), err)
+				break
+			}
+		}
+		ran++
+	}
+
+	if err == nil {
+		err = action()
+	} else if stderrors.Is(err, ErrSkipAction) {
+		err = nil
+	}
+
+	for idx := ran - 1; idx >= 0; idx-- {
+		if mw := chain[idx]; mw.After != nil {
+			cmd.log().Tracef("running middleware %s after %s", mw.Name, cmd.FullName())
+			err = mw.After(cmd, err)
+		}
+	}
+
+	return err
+}
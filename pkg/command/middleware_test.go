@@ -0,0 +1,166 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package command_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	. "git.rob.mx/nidito/chinampa/pkg/command"
+	"github.com/spf13/cobra"
+)
+
+func TestMiddleware(t *testing.T) {
+	recorder := func(calls *[]string, name string, before error) Middleware {
+		return Middleware{
+			Name: name,
+			Before: func(cmd *Command) error {
+				*calls = append(*calls, "before "+name)
+				return before
+			},
+			After: func(cmd *Command, err error) error {
+				*calls = append(*calls, fmt.Sprintf("after %s: %v", name, err))
+				return err
+			},
+		}
+	}
+
+	cases := []struct {
+		Name     string
+		Inherit  []error
+		Own      []error
+		Action   error
+		Expected []string
+		Error    string
+	}{
+		{
+			Name:    "runs in order",
+			Inherit: []error{nil},
+			Own:     []error{nil},
+			Expected: []string{
+				"before inherited-0",
+				"before own-0",
+				"action",
+				"after own-0: <nil>",
+				"after inherited-0: <nil>",
+			},
+		},
+		{
+			Name:    "after hooks observe action failures",
+			Inherit: []error{nil},
+			Own:     []error{nil},
+			Action:  fmt.Errorf("failed"),
+			Error:   "failed",
+			Expected: []string{
+				"before inherited-0",
+				"before own-0",
+				"action",
+				"after own-0: failed",
+				"after inherited-0: failed",
+			},
+		},
+		{
+			Name:    "before hooks short-circuit with errors",
+			Inherit: []error{nil, fmt.Errorf("denied")},
+			Own:     []error{nil},
+			Error:   "denied",
+			Expected: []string{
+				"before inherited-0",
+				"before inherited-1",
+				"after inherited-0: denied",
+			},
+		},
+		{
+			Name:    "before hooks skip actions",
+			Inherit: []error{nil},
+			Own:     []error{ErrSkipAction},
+			Expected: []string{
+				"before inherited-0",
+				"before own-0",
+				"after inherited-0: <nil>",
+			},
+		},
+		{
+			Name:    "before hooks skip actions with wrapped errors",
+			Inherit: []error{fmt.Errorf("not today: %w", ErrSkipAction)},
+			Own:     []error{nil},
+			Expected: []string{
+				"before inherited-0",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			calls := []string{}
+			inherited := []Middleware{}
+			for idx, err := range c.Inherit {
+				inherited = append(inherited, recorder(&calls, fmt.Sprintf("inherited-%d", idx), err))
+			}
+			own := []Middleware{}
+			for idx, err := range c.Own {
+				own = append(own, recorder(&calls, fmt.Sprintf("own-%d", idx), err))
+			}
+
+			cmd := (&Command{
+				Path:       []string{"test", "middleware"},
+				Middleware: own,
+				Action: func(cmd *Command) error {
+					calls = append(calls, "action")
+					return c.Action
+				},
+			}).SetBindings()
+			cmd.InheritMiddleware(inherited...)
+
+			err := cmd.Run(&cobra.Command{}, []string{})
+			if c.Error == "" && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			} else if c.Error != "" && (err == nil || err.Error() != c.Error) {
+				t.Fatalf("expected error %s, got %v", c.Error, err)
+			}
+
+			if !reflect.DeepEqual(calls, c.Expected) {
+				t.Fatalf("unexpected calls:\ngot   : %v\nwanted: %v", calls, c.Expected)
+			}
+		})
+	}
+}
+
+func TestMiddlewareMutatesInput(t *testing.T) {
+	var received any
+	cmd := (&Command{
+		Path: []string{"test", "middleware"},
+		Arguments: Arguments{
+			{Name: "first"},
+		},
+		Options: Options{
+			"option": {Default: "default"},
+		},
+		Middleware: []Middleware{
+			{
+				Before: func(cmd *Command) error {
+					cmd.Arguments[0].SetValue([]string{"changed"})
+					cmd.Options["option"].SetValue("changed")
+					return nil
+				},
+			},
+		},
+		Action: func(cmd *Command) error {
+			received = []any{cmd.Arguments[0].ToValue(), cmd.Options["option"].ToValue()}
+			return nil
+		},
+	}).SetBindings()
+
+	cc := &cobra.Command{}
+	cc.Flags().AddFlagSet(cmd.FlagSet())
+	if err := cmd.Run(cc, []string{"original"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []any{"changed", "changed"}
+	if !reflect.DeepEqual(received, expected) {
+		t.Fatalf("action received %v, wanted %v", received, expected)
+	}
+}
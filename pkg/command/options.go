@@ -4,11 +4,15 @@ package command
 
 import (
 	"fmt"
+	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"git.rob.mx/nidito/chinampa/pkg/config"
+	"git.rob.mx/nidito/chinampa/pkg/env"
 	"git.rob.mx/nidito/chinampa/pkg/errors"
-	"git.rob.mx/nidito/chinampa/pkg/logger"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
@@ -34,68 +38,263 @@ func (opts *Options) AllKnownStr() map[string]string {
 	return col
 }
 
-// Parse populates values with those supplied in the provided pflag.Flagset.
-func (opts *Options) Parse(supplied *pflag.FlagSet) {
-	// log.Debugf("Parsing supplied flags, %v", supplied)
-	for name, opt := range *opts {
-		switch opt.Type {
-		case ValueTypeBoolean:
-			if val, err := supplied.GetBool(name); err == nil {
-				opt.provided = val
-				continue
+// names returns the sorted names of these options.
+func (opts *Options) names() []string {
+	names := make([]string, 0, len(*opts))
+	for name := range *opts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Parse populates values with those supplied in the provided pflag.Flagset or, for flags not provided, the
+// environment and then config files, read only if any option is missing a value.
+func (opts *Options) Parse(supplied *pflag.FlagSet) error {
+	problems := []error{}
+	var layers config.Layers
+	configLoaded := false
+	for _, name := range opts.names() {
+		opt := (*opts)[name]
+		opt.source = Source{Kind: SourceDefault}
+		opt.parseFlag(name, supplied)
+		if flag := supplied.Lookup(name); flag != nil && flag.Changed {
+			opt.source = Source{Kind: SourceFlag, Name: "--" + name}
+			continue
+		}
+
+		if found, err := opt.parseEnv(name); found || err != nil {
+			problems = append(problems, err)
+			continue
+		}
+
+		if !configLoaded {
+			configLoaded = true
+			var err error
+			if layers, err = opt.configLayers(); err != nil {
+				problems = append(problems, err)
 			}
-		case ValueTypeInt:
-			if val, err := supplied.GetInt(name); err == nil {
+		}
+		problems = append(problems, opt.parseConfig(name, layers))
+	}
+
+	return errors.Combine(problems...)
+}
+
+func (opt *Option) parseFlag(name string, supplied *pflag.FlagSet) {
+	if flag := supplied.Lookup(name); flag != nil {
+		if val, ok := flag.Value.(*typedFlag); ok {
+			opt.provided = val.value
+			return
+		}
+	}
+
+	switch opt.Type {
+	case ValueTypeBoolean:
+		if val, err := supplied.GetBool(name); err == nil {
+			opt.provided = val
+		}
+	case ValueTypeInt:
+		if val, err := supplied.GetInt(name); err == nil {
+			opt.provided = val
+		}
+	case ValueTypeFloat, ValueTypeDuration, ValueTypeTime, ValueTypeURL, ValueTypeIP, ValueTypeCIDR, ValueTypeByteSize, ValueTypeMap, ValueTypeCount:
+		return
+	default:
+		opt.Type = ValueTypeString
+		if opt.Repeated {
+			val, err := supplied.GetStringArray(name)
+			if err == nil {
 				opt.provided = val
-				continue
+				return
 			}
-		default:
-			opt.Type = ValueTypeString
-			if opt.Repeated {
-				val, err := supplied.GetStringArray(name)
-				if err == nil {
-					opt.provided = val
-					continue
-				}
-				logger.Errorf("Invalid option configuration: %s", err)
-			} else {
-				if val, err := supplied.GetString(name); err == nil {
-					opt.provided = val
-					continue
-				}
+			opt.Command.log().Errorf("Invalid option configuration: %s", err)
+		} else {
+			if val, err := supplied.GetString(name); err == nil {
+				opt.provided = val
 			}
 		}
 	}
 }
 
-// AreValid tells if these options are all valid.
-func (opts *Options) AreValid() error {
-	for name, opt := range *opts {
-		if err := opt.Validate(name); err != nil {
-			return err
+func (opt *Option) parseEnv(name string) (found bool, err error) {
+	envName := opt.EnvName(name)
+	if envName == "" {
+		return false, nil
+	}
+
+	raw := os.Getenv(envName)
+	if raw == "" {
+		return false, nil
+	}
+
+	values := []string{raw}
+	if opt.isList() {
+		delimiter := opt.EnvDelimiter
+		if delimiter == "" {
+			delimiter = ","
 		}
+		values = strings.Split(raw, delimiter)
+	}
+
+	value, err := opt.parseStrings(values)
+	if err != nil {
+		return true, errors.BadArguments{Msg: fmt.Sprintf("%s is not a valid %s for option <%s> from %s: %s", raw, opt.Type, name, envName, err), Option: name}
+	}
+
+	opt.Command.log().Debugf("Using value from %s for option <%s>", envName, name)
+	opt.provided = value
+	opt.source = Source{Kind: SourceEnv, Name: envName}
+	return true, nil
+}
+
+// configLayers returns the config files of the current invocation of this option's command, if any.
+func (opt *Option) configLayers() (config.Layers, error) {
+	if opt.Command == nil || opt.Command.config == nil {
+		return nil, nil
+	}
+	return opt.Command.config()
+}
+
+func (opt *Option) parseConfig(name string, layers config.Layers) error {
+	if len(layers) == 0 {
+		return nil
 	}
 
+	raw, layer, found := layers.Lookup(opt.Command.configPath(), name)
+	if !found {
+		return nil
+	}
+
+	key := layer.Key(opt.Command.configPath(), name)
+	values, err := opt.configStrings(raw)
+	if err != nil {
+		return config.Invalid(layer.Path, key, fmt.Errorf("invalid value for option <%s>: %w", name, err))
+	}
+
+	value, err := opt.parseStrings(values)
+	if err != nil {
+		return config.Invalid(layer.Path, key, fmt.Errorf("%v is not a valid %s for option <%s>: %w", raw, opt.Type, name, err))
+	}
+
+	opt.Command.log().Debugf("Using value from %s for option <%s>", layer.Path, name)
+	opt.provided = value
+	opt.source = Source{Kind: SourceConfig, Name: layer.Path}
 	return nil
 }
 
+// isList tells if this option holds a list of values.
+func (opt *Option) isList() bool {
+	return opt.Repeated && opt.Type != ValueTypeMap && opt.Type != ValueTypeCount
+}
+
+// parseStrings returns the value represented by values, of which only repeated options may have more than one.
+func (opt *Option) parseStrings(values []string) (any, error) {
+	value := &typedFlag{kind: opt.Type, repeated: opt.isList()}
+	for _, current := range values {
+		if err := value.Set(current); err != nil {
+			return nil, err
+		}
+	}
+	return value.value, nil
+}
+
+// configStrings returns the string representations of a value decoded from a config file.
+func (opt *Option) configStrings(raw any) ([]string, error) {
+	switch value := raw.(type) {
+	case []any:
+		if !opt.isList() {
+			return nil, fmt.Errorf("expected a single value, got a list")
+		}
+		res := make([]string, 0, len(value))
+		for _, item := range value {
+			res = append(res, configString(item))
+		}
+		return res, nil
+	case map[string]any:
+		if opt.Type != ValueTypeMap {
+			return nil, fmt.Errorf("expected a single value, got a map")
+		}
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		res := make([]string, 0, len(value))
+		for _, key := range keys {
+			res = append(res, key+"="+configString(value[key]))
+		}
+		return res, nil
+	}
+
+	return []string{configString(raw)}, nil
+}
+
+func configString(value any) string {
+	if t, ok := value.(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+	return fmt.Sprint(value)
+}
+
+// AreValid returns an error listing every invalid option, sorted by name.
+func (opts *Options) AreValid() error {
+	problems := []error{}
+	for _, name := range opts.names() {
+		problems = append(problems, (*opts)[name].Validate(name))
+	}
+
+	return errors.Combine(problems...)
+}
+
 // Option represents a command line flag.
 type Option struct {
 	// Type represents the type of value expected to be provided for this option.
-	Type ValueType `json:"type" yaml:"type" validate:"omitempty,oneof=string bool int"`
+	Type ValueType `json:"type" yaml:"type" validate:"omitempty,oneof=string bool int float duration time url ip cidr bytesize map count"`
 	// Description is a required field that show up during completions and help.
 	Description string `json:"description" yaml:"description" validate:"required"`
 	// Default value for this option, if none provided.
-	Default any `json:"default,omitempty" yaml:"default,omitempty"`
+	Default any `json:"default,omitempty" yaml:"default,omitempty" validate:"excluded_with=Required"`
 	// ShortName When set, enables representing this Option as a short flag (-x).
 	ShortName string `json:"short-name,omitempty" yaml:"short-name,omitempty"` // nolint:tagliatelle
 	// Values denote the source for completion/validation values of this option.
 	Values *ValueSource `json:"values,omitempty" yaml:"values,omitempty" validate:"omitempty"`
 	// Repeated options may be specified more than once.
 	Repeated bool `json:"repeated" yaml:"repeated" validate:"omitempty"`
+	// Validation checks user-supplied values, in addition to Values.
+	Validation *Validation `json:"validation,omitempty" yaml:"validation,omitempty" validate:"omitempty"`
+	// Required raises an error if an option is not provided as a flag, environment variable or in a config file.
+	Required bool `json:"required,omitempty" yaml:"required,omitempty" validate:"excluded_with=Default"`
+	// Env names an environment variable supplying this option's value when not provided as a flag.
+	Env string `json:"env,omitempty" yaml:"env,omitempty" validate:"omitempty,excludesall=!$\\/%^@#?:'\" "`
+	// Secret values are masked when prompted for, and redacted when explaining an invocation.
+	Secret bool `json:"secret,omitempty" yaml:"secret,omitempty"`
+	// EnvDelimiter separates the values of a repeated option supplied through Env, a comma by default.
+	EnvDelimiter string `json:"env-delimiter,omitempty" yaml:"env-delimiter,omitempty"` // nolint:tagliatelle
 	// Command references the Command this Option is defined for.
 	Command  *Command `json:"-" yaml:"-" validate:"-"`
 	provided any
+	source   Source
+}
+
+// EnvName returns the name of the environment variable supplying the value of the option called name, either
+// Env or, when the invocation's env.Names has an OptionPrefix, one derived from it, i.e. MY_APP_DRY_RUN for
+// --dry-run.
+func (opt *Option) EnvName(name string) string {
+	if opt.Env != "" {
+		return opt.Env
+	}
+
+	prefix := env.OptionPrefix
+	if opt.Command != nil {
+		prefix = opt.Command.Runtime().Env.OptionPrefix
+	}
+
+	if prefix == "" {
+		return ""
+	}
+
+	return prefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
 }
 
 // IsKnown tells if the option was provided by the user.
@@ -103,6 +302,20 @@ func (opt *Option) IsKnown() bool {
 	return opt.provided != nil
 }
 
+// SetValue overrides the resolved value for an option.
+func (opt *Option) SetValue(value any) {
+	opt.provided = value
+	opt.source = Source{Kind: SourceOverride}
+}
+
+// Source tells where the resolved value for this option came from.
+func (opt *Option) Source() Source {
+	if opt.source.Kind == "" {
+		return Source{Kind: SourceDefault}
+	}
+	return opt.source
+}
+
 // Returns the resolved value for an option.
 func (opt *Option) ToValue() any {
 	if opt.IsKnown() {
@@ -114,6 +327,10 @@ func (opt *Option) ToValue() any {
 // Returns a string representation of this Option's resolved value.
 func (opt *Option) ToString() string {
 	value := opt.ToValue()
+	if opt.Repeated {
+		return opt.Type.Format(value)
+	}
+
 	stringValue := ""
 	switch opt.Type {
 	case ValueTypeBoolean:
@@ -129,14 +346,25 @@ func (opt *Option) ToString() string {
 			stringValue = fmt.Sprintf("%d", value)
 		}
 	default:
-		if value != nil {
-			stringValue = value.(string)
-		}
+		stringValue = opt.Type.Format(value)
 	}
 
 	return stringValue
 }
 
+// ToDesc prints out the usage of the option called name, i.e. --name NAME.
+func (opt *Option) ToDesc(name string) string {
+	if opt.Type == ValueTypeBoolean || opt.Type == ValueTypeCount {
+		return "--" + name
+	}
+	return "--" + name + " " + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// DefaultString returns a string representation of this Option's default value.
+func (opt *Option) DefaultString() string {
+	return opt.Type.Format(opt.Default)
+}
+
 func (opt *Option) internalValidate(name, current string) error {
 	if current == "" {
 		return nil
@@ -148,32 +376,31 @@ func (opt *Option) internalValidate(name, current string) error {
 	}
 
 	if !contains(validValues, current) {
-		return errors.BadArguments{Msg: fmt.Sprintf("%s is not a valid value for option <%s>. Valid options are: %s", current, name, strings.Join(validValues, ", "))}
+		return errors.BadArguments{Msg: fmt.Sprintf("%s is not a valid value for option <%s>. Valid options are: %s", current, name, strings.Join(validValues, ", ")), Option: name}
 	}
 
 	return nil
 }
 
-// Validate validates the provided value if a value source.
+// Validate validates the provided value against its value source and Validation.
 func (opt *Option) Validate(name string) error {
-	if !opt.Validates() {
-		return nil
+	values := []string{opt.ToString()}
+	if opt.Repeated && opt.Type != ValueTypeMap {
+		values = formatEach(opt.Type, opt.ToValue())
 	}
 
-	if opt.Repeated {
-		values := opt.ToValue().([]string)
+	problems := []error{}
+	if opt.Validates() {
 		for _, current := range values {
-			if err := opt.internalValidate(name, current); err != nil {
-				return err
-			}
-		}
-	} else {
-		if err := opt.internalValidate(name, opt.ToString()); err != nil {
-			return err
+			problems = append(problems, opt.internalValidate(name, current))
 		}
 	}
 
-	return nil
+	if opt.Source().Kind != SourceDefault {
+		problems = append(problems, validateEach(opt.Validation, opt.Type, values, fmt.Sprintf("option <%s>", name), errors.BadArguments{Option: name}))
+	}
+
+	return errors.Combine(problems...)
 }
 
 // Validates tells if the user-supplied value needs validation.
@@ -201,16 +428,19 @@ func (opt *Option) Resolve(currentValue string) (values []string, flag cobra.She
 // CompletionFunction is called by cobra when asked to complete an option.
 func (opt *Option) CompletionFunction(cmd *cobra.Command, args []string, toComplete string) (values []string, flag cobra.ShellCompDirective) {
 	if !opt.providesAutocomplete() {
-		logger.Tracef("Option does not provide autocomplete %+v", opt)
+		opt.Command.log().Tracef("Option does not provide autocomplete %+v", opt)
 		flag = cobra.ShellCompDirectiveNoFileComp
 		return
 	}
 
 	if err := opt.Command.Arguments.Parse(args); err != nil {
-		logger.Errorf("Could not parse command arguments %s", err)
+		opt.Command.log().Errorf("Could not parse command arguments %s", err)
+		return []string{}, cobra.ShellCompDirectiveDefault
+	}
+	if err := opt.Command.Options.Parse(cmd.Flags()); err != nil {
+		opt.Command.log().Errorf("Could not parse command options %s", err)
 		return []string{}, cobra.ShellCompDirectiveDefault
 	}
-	opt.Command.Options.Parse(cmd.Flags())
 
 	var err error
 	values, flag, err = opt.Resolve(toComplete)
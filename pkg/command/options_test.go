@@ -0,0 +1,274 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package command_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "git.rob.mx/nidito/chinampa/pkg/command"
+	"git.rob.mx/nidito/chinampa/pkg/env"
+	"git.rob.mx/nidito/chinampa/pkg/errors"
+	"git.rob.mx/nidito/chinampa/pkg/statuscode"
+	"github.com/spf13/cobra"
+)
+
+func envCommand() *Command {
+	return (&Command{
+		Path: []string{"test", "env"},
+		Options: Options{
+			"name":    {Default: "default", Env: "TEST_NAME"},
+			"count":   {Type: ValueTypeInt, Default: 1},
+			"tags":    {Repeated: true, Env: "TEST_TAGS", EnvDelimiter: ":"},
+			"timeout": {Type: ValueTypeDuration, Default: "1s"},
+		},
+	}).SetBindings()
+}
+
+func TestOptionsFromEnv(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Prefix   string
+		Env      map[string]string
+		Flags    []string
+		Expected map[string]any
+		Error    string
+	}{
+		{
+			Name: "defaults",
+			Expected: map[string]any{
+				"name":    "default",
+				"count":   1,
+				"tags":    []string{},
+				"timeout": "1s",
+			},
+		},
+		{
+			Name: "explicit env",
+			Env:  map[string]string{"TEST_NAME": "from-env", "TEST_TAGS": "a:b", "TEST_COUNT": "3"},
+			Expected: map[string]any{
+				"name":    "from-env",
+				"count":   1,
+				"tags":    []string{"a", "b"},
+				"timeout": "1s",
+			},
+		},
+		{
+			Name:   "prefixed env",
+			Prefix: "TEST_",
+			Env:    map[string]string{"TEST_COUNT": "3", "TEST_TIMEOUT": "1m"},
+			Expected: map[string]any{
+				"name":    "default",
+				"count":   3,
+				"tags":    []string{},
+				"timeout": "1m0s",
+			},
+		},
+		{
+			Name:   "flags win",
+			Prefix: "TEST_",
+			Env:    map[string]string{"TEST_NAME": "from-env", "TEST_COUNT": "3", "TEST_TAGS": "a:b"},
+			Flags:  []string{"--name", "from-flag", "--count", "2", "--tags", "c"},
+			Expected: map[string]any{
+				"name":    "from-flag",
+				"count":   2,
+				"tags":    []string{"c"},
+				"timeout": "1s",
+			},
+		},
+		{
+			Name:   "bad env",
+			Prefix: "TEST_",
+			Env:    map[string]string{"TEST_COUNT": "many"},
+			Error:  "many is not a valid int for option <count> from TEST_COUNT",
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			prefix := env.OptionPrefix
+			env.OptionPrefix = c.Prefix
+			t.Cleanup(func() { env.OptionPrefix = prefix })
+			for k, v := range c.Env {
+				t.Setenv(k, v)
+			}
+
+			cmd := envCommand()
+			fs := cmd.FlagSet()
+			if err := fs.Parse(c.Flags); err != nil {
+				t.Fatalf("could not parse flags: %s", err)
+			}
+
+			err := cmd.Options.Parse(fs)
+			if c.Error != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), c.Error) {
+					t.Fatalf("expected error <%s>, got <%v>", c.Error, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			known := cmd.Options.AllKnown()
+			known["timeout"] = cmd.Options["timeout"].ToString()
+			if !reflect.DeepEqual(known, c.Expected) {
+				t.Fatalf("unexpected values:\ngot   : %#v\nwanted: %#v", known, c.Expected)
+			}
+		})
+	}
+}
+
+func TestOptionEnvName(t *testing.T) {
+	prefix := env.OptionPrefix
+	t.Cleanup(func() { env.OptionPrefix = prefix })
+
+	opt := &Option{}
+	env.OptionPrefix = ""
+	if name := opt.EnvName("dry-run"); name != "" {
+		t.Fatalf("unexpected env name without prefix: %s", name)
+	}
+
+	env.OptionPrefix = "MY_APP_"
+	if name := opt.EnvName("dry-run"); name != "MY_APP_DRY_RUN" {
+		t.Fatalf("unexpected derived env name: %s", name)
+	}
+
+	opt.Env = "DRY"
+	if name := opt.EnvName("dry-run"); name != "DRY" {
+		t.Fatalf("unexpected explicit env name: %s", name)
+	}
+}
+
+func TestOptionsFromConfig(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	err := os.WriteFile(file, []byte(`
+name: from-config
+test:
+  env:
+    count: 3
+    tags: [a, b]
+    timeout: 1m
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := envCommand()
+	cc := &cobra.Command{Use: "env"}
+	cc.Flags().AddFlagSet(cmd.FlagSet())
+	cc.Flags().String("config", "", "config file")
+	t.Setenv("TEST_NAME", "from-env")
+	if err := cc.Flags().Parse([]string{"--config", file, "--count", "2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cmd.ParseInput(cc, []string{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	known := cmd.Options.AllKnown()
+	known["timeout"] = cmd.Options["timeout"].ToString()
+	expected := map[string]any{
+		"name":    "from-env",
+		"count":   2,
+		"tags":    []string{"a", "b"},
+		"timeout": "1m0s",
+	}
+	if !reflect.DeepEqual(known, expected) {
+		t.Fatalf("unexpected values:\ngot   : %#v\nwanted: %#v", known, expected)
+	}
+
+	sources := map[string]Source{}
+	for name, opt := range cmd.Options {
+		sources[name] = opt.Source()
+	}
+	expectedSources := map[string]Source{
+		"name":    {Kind: SourceEnv, Name: "TEST_NAME"},
+		"count":   {Kind: SourceFlag, Name: "--count"},
+		"tags":    {Kind: SourceConfig, Name: file},
+		"timeout": {Kind: SourceConfig, Name: file},
+	}
+	if !reflect.DeepEqual(sources, expectedSources) {
+		t.Fatalf("unexpected sources:\ngot   : %#v\nwanted: %#v", sources, expectedSources)
+	}
+
+	explained := cmd.Explain()
+	expectedExplain := `Command: test env
+Options:
+  --count = 2 (flag --count)
+  --name = from-env (environment variable TEST_NAME)
+  --tags = a,b (config file ` + file + `)
+  --timeout = 1m0s (config file ` + file + `)
+`
+	if explained != expectedExplain {
+		t.Fatalf("unexpected explanation:\ngot   : %s\nwanted: %s", explained, expectedExplain)
+	}
+
+	if err := os.WriteFile(file, []byte("count: [1, 2]"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cmd = envCommand()
+	cc = &cobra.Command{Use: "env"}
+	cc.Flags().AddFlagSet(cmd.FlagSet())
+	cc.Flags().String("config", file, "config file")
+	err = cmd.ParseInput(cc, []string{})
+	if err == nil || err.Error() != "could not parse config file "+file+" at key count: invalid value for option <count>: expected a single value, got a list" {
+		t.Fatalf("expected error, got %v", err)
+	}
+	if code := errors.NewRegistry().Report(cc, err).Code; code != statuscode.ConfigError {
+		t.Fatalf("expected config error status code, got %d", code)
+	}
+}
+
+func TestOptionsReadConfigOnlyWhenNeeded(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(file, []byte("count: [1"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		Name  string
+		Cmd   *Command
+		Flags []string
+		Env   string
+		Fails bool
+	}{
+		{Name: "no options", Cmd: (&Command{Path: []string{"test", "plain"}}).SetBindings()},
+		{Name: "all supplied", Cmd: envCommand(), Flags: []string{"--count", "2", "--timeout", "2s"}, Env: "a:b"},
+		{Name: "missing values", Cmd: envCommand(), Flags: []string{"--count", "2"}, Fails: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			t.Setenv("TEST_NAME", "from-env")
+			t.Setenv("TEST_TAGS", c.Env)
+			cc := &cobra.Command{Use: c.Cmd.Name()}
+			cc.Flags().AddFlagSet(c.Cmd.FlagSet())
+			cc.Flags().String("config", file, "config file")
+			if err := cc.Flags().Parse(c.Flags); err != nil {
+				t.Fatal(err)
+			}
+
+			err := c.Cmd.ParseInput(cc, []string{})
+			if !c.Fails {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				return
+			}
+
+			if code := errors.NewRegistry().Report(cc, err).Code; err == nil || code != statuscode.ConfigError {
+				t.Fatalf("expected config error, got %d: %v", code, err)
+			}
+			if strings.Count(err.Error(), file) != 1 {
+				t.Fatalf("config error reported more than once: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package command
+
+import (
+	stderrors "errors"
+	"fmt"
+	"strings"
+
+	"git.rob.mx/nidito/chinampa/pkg/errors"
+	"git.rob.mx/nidito/chinampa/pkg/prompt"
+	"git.rob.mx/nidito/chinampa/pkg/statuscode"
+	"github.com/spf13/cobra"
+)
+
+// promptMissing asks for the values of missing required arguments and options, unless input is disabled or
+// prompt.PrompterFunc returns no Prompter for the streams of this invocation, i.e. when they're not terminals.
+func (cmd *Command) promptMissing() error {
+	if !cmd.Runtime().InputEnabled() {
+		return nil
+	}
+
+	args := []*Argument{}
+	for _, arg := range cmd.Arguments {
+		if arg != nil && arg.Required && !arg.IsKnown() {
+			args = append(args, arg)
+		}
+	}
+
+	opts := []string{}
+	for _, name := range cmd.Options.names() {
+		opt := cmd.Options[name]
+		if opt != nil && opt.Required && !cmd.isProvided(name) && opt.Type != ValueTypeBoolean && opt.Type != ValueTypeCount {
+			opts = append(opts, name)
+		}
+	}
+
+	if len(args) == 0 && len(opts) == 0 {
+		return nil
+	}
+
+	prompter := prompt.PrompterFunc(cmd.Stdin(), cmd.Stderr())
+	if prompter == nil {
+		cmd.log().Debugf("Not prompting for missing values of %s outside a terminal", cmd.FullName())
+		return nil
+	}
+
+	for _, arg := range args {
+		if err := arg.prompt(prompter); err != nil {
+			return promptError(err)
+		}
+	}
+
+	for _, name := range opts {
+		if err := cmd.Options[name].prompt(name, prompter); err != nil {
+			return promptError(err)
+		}
+	}
+
+	return nil
+}
+
+func promptError(err error) error {
+	if stderrors.Is(err, prompt.ErrInterrupted) {
+		return errors.ExitError{Code: statuscode.Interrupted, Err: err}
+	}
+	return err
+}
+
+// ask prompts for a value, selecting one of those resolved by values unless secret or values resolve files or
+// directories, calling set with every value typed until it returns no error.
+func ask(prompter prompt.Prompter, label string, values *ValueSource, secret bool, set func(value string) error) error {
+	if values != nil && !secret {
+		options, directive, err := values.Resolve("")
+		files := directive&(cobra.ShellCompDirectiveFilterFileExt|cobra.ShellCompDirectiveFilterDirs) != 0
+		if err == nil && len(options) > 0 && !files {
+			value, err := prompter.Select(label, options)
+			if err != nil {
+				return err
+			}
+			return set(value)
+		}
+	}
+
+	validate := func(value string) error {
+		if strings.TrimSpace(value) == "" {
+			return fmt.Errorf("a value is required")
+		}
+		return set(value)
+	}
+
+	if secret {
+		_, err := prompter.Secret(label, validate)
+		return err
+	}
+	_, err := prompter.Input(label, validate)
+	return err
+}
+
+// prompt asks for the value of this argument, splitting variadic ones on whitespace.
+func (arg *Argument) prompt(prompter prompt.Prompter) error {
+	if arg.Values != nil && arg.Values.command == nil {
+		arg.Values.command = arg.Command
+	}
+
+	values := arg.Values
+	if arg.Variadic {
+		values = nil
+	}
+
+	return ask(prompter, fmt.Sprintf("%s (%s)", arg.Name, arg.Description), values, arg.Secret, func(value string) error {
+		supplied := []string{value}
+		if arg.Variadic {
+			supplied = strings.Fields(value)
+		}
+
+		for _, current := range supplied {
+			if _, err := arg.Type.Parse(current); err != nil {
+				return errors.BadArguments{Msg: fmt.Sprintf("%s is not a valid %s for argument <%s>: %s", current, arg.Type, arg.Name, err), Argument: arg.Name}
+			}
+		}
+
+		arg.provided = &supplied
+		arg.source = Source{Kind: SourcePrompt}
+		return arg.Validate()
+	})
+}
+
+// prompt asks for the value of this option, called name, splitting repeated ones on whitespace.
+func (opt *Option) prompt(name string, prompter prompt.Prompter) error {
+	if opt.Values != nil && opt.Values.command == nil {
+		opt.Values.command = opt.Command
+	}
+
+	values := opt.Values
+	if opt.isList() || opt.Type == ValueTypeMap {
+		values = nil
+	}
+
+	return ask(prompter, fmt.Sprintf("--%s (%s)", name, opt.Description), values, opt.Secret, func(value string) error {
+		supplied := []string{value}
+		if opt.isList() || opt.Type == ValueTypeMap {
+			supplied = strings.Fields(value)
+		}
+
+		parsed, err := opt.parseStrings(supplied)
+		if err != nil {
+			return errors.BadArguments{Msg: fmt.Sprintf("%s is not a valid %s for option <%s>: %s", value, opt.Type, name, err), Option: name}
+		}
+
+		opt.provided = parsed
+		opt.source = Source{Kind: SourcePrompt}
+		return opt.Validate(name)
+	})
+}
@@ -7,44 +7,79 @@ import (
 	"git.rob.mx/nidito/chinampa/pkg/runtime"
 )
 
-var Root = &Command{
-	Summary:     "Replace me with chinampa.Configure",
-	Description: "",
-	Path:        []string{runtime.Executable},
-	Options: Options{
-		_c.HelpCommandName: &Option{
-			ShortName:   "h",
-			Type:        "bool",
-			Description: "Display help for any command",
-		},
-		"color": &Option{
-			Type:        "bool",
-			Description: "Always print colors to stderr",
-			Default:     runtime.ColorEnabled(),
-		},
-		"no-color": &Option{
-			Type:        "bool",
-			Description: "Disable printing of colors to stderr",
-			Default:     !runtime.ColorEnabled(),
-		},
-		"verbose": &Option{
-			ShortName:   "v",
-			Type:        "bool",
-			Default:     runtime.VerboseEnabled(),
-			Description: "Log verbose output to stderr",
-		},
-		"silent": &Option{
-			Type:        "bool",
-			Description: "Silence non-error logging",
-		},
-		"skip-validation": &Option{
-			Type:        "bool",
-			Description: "Do not validate any arguments or options",
-		},
-		"version": &Option{
-			Type:        "bool",
-			Default:     false,
-			Description: "Display program version and exit",
+// Root is the root command of the default chinampa app.
+var Root = NewRoot(runtime.Executable)
+
+// NewRoot returns a root command for a program called name, with chinampa's global options.
+func NewRoot(name string) *Command {
+	return &Command{
+		Summary:     "Replace me with chinampa.Configure",
+		Description: "",
+		Path:        []string{name},
+		Options: Options{
+			_c.HelpCommandName: &Option{
+				ShortName:   "h",
+				Type:        "bool",
+				Description: "Display help for any command",
+			},
+			"color": &Option{
+				Type:        "bool",
+				Description: "Always print colors to stderr",
+				Default:     runtime.ColorEnabled(),
+			},
+			"no-color": &Option{
+				Type:        "bool",
+				Description: "Disable printing of colors to stderr",
+				Default:     !runtime.ColorEnabled(),
+			},
+			"verbose": &Option{
+				ShortName:   "v",
+				Type:        ValueTypeCount,
+				Default:     runtime.Verbosity(),
+				Description: "Log verbose output to stderr, repeat to increase verbosity up to -vvv",
+			},
+			"silent": &Option{
+				Type:        "bool",
+				Description: "Silence non-error logging",
+			},
+			"config": &Option{
+				Description: "Read option values from this config file, over those found in system, user and project config files",
+				Values:      &ValueSource{Files: &[]string{"yaml", "yml", "toml", "json"}},
+			},
+			"explain": &Option{
+				Type:        "bool",
+				Description: "Print the resolved arguments and options, and where their values came from, to stderr before running",
+			},
+			"error-format": &Option{
+				Description: "Print errors to stderr as text, or json for programs wrapping this one",
+				Default:     runtime.ErrorFormat(),
+				Values:      &ValueSource{Static: &[]string{"text", "json"}},
+			},
+			"output": &Option{
+				Description: "Render output as plain text, json, yaml, a table, a wide table or the template given with --format",
+				Default:     "plain",
+				Values:      &ValueSource{Static: &[]string{"plain", "json", "yaml", "table", "wide", "template"}},
+			},
+			"format": &Option{
+				Description: "Render output with this Go template, i.e. '{{ .Name }}'",
+			},
+			"no-input": &Option{
+				Type:        "bool",
+				Description: "Never prompt for missing values, even when running on a terminal",
+			},
+			"yes": &Option{
+				Type:        "bool",
+				Description: "Run destructive commands without asking for confirmation",
+			},
+			"skip-validation": &Option{
+				Type:        "bool",
+				Description: "Do not validate any arguments or options",
+			},
+			"version": &Option{
+				Type:        "bool",
+				Default:     false,
+				Description: "Display program version and exit",
+			},
 		},
-	},
+	}
 }
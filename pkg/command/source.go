@@ -0,0 +1,121 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package command
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// SourceKind tells where a value came from.
+type SourceKind string
+
+const (
+	// SourceDefault values come from an Argument or Option's Default.
+	SourceDefault SourceKind = "default"
+	// SourceArgument values were supplied as positional arguments.
+	SourceArgument SourceKind = "argument"
+	// SourceFlag values were supplied as flags.
+	SourceFlag SourceKind = "flag"
+	// SourceEnv values were read from environment variables.
+	SourceEnv SourceKind = "env"
+	// SourceConfig values were read from config files.
+	SourceConfig SourceKind = "config"
+	// SourceOverride values were set with SetValue.
+	SourceOverride SourceKind = "override"
+	// SourcePrompt values were typed or selected by the user when prompted.
+	SourcePrompt SourceKind = "prompt"
+)
+
+// redacted replaces the values of secret arguments and options when explaining an invocation.
+const redacted = "********"
+
+// Source records where a resolved value came from.
+type Source struct {
+	Kind SourceKind
+	// Name identifies the flag, argument position, environment variable or config file supplying the value.
+	Name string
+}
+
+func (src Source) String() string {
+	switch src.Kind {
+	case SourceArgument:
+		return "argument #" + src.Name
+	case SourceFlag:
+		return "flag " + src.Name
+	case SourceEnv:
+		return "environment variable " + src.Name
+	case SourceConfig:
+		return "config file " + src.Name
+	case SourceOverride:
+		return "set by program"
+	case SourcePrompt:
+		return "prompted"
+	}
+	return string(SourceDefault)
+}
+
+// Explain returns a description of the resolved invocation of this command, with every argument and option
+// alongside its value and source.
+func (cmd *Command) Explain() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Command: %s\n", cmd.FullName())
+
+	if len(cmd.Arguments) > 0 {
+		sb.WriteString("Arguments:\n")
+		for _, arg := range cmd.Arguments {
+			value := arg.ToString()
+			if arg.Secret && value != "" {
+				value = redacted
+			}
+			fmt.Fprintf(&sb, "  %s = %s (%s)\n", arg.Name, value, arg.Source())
+		}
+	}
+
+	if len(cmd.Options) > 0 {
+		sb.WriteString("Options:\n")
+		for _, name := range cmd.Options.names() {
+			opt := cmd.Options[name]
+			value := opt.ToString()
+			if opt.Secret && value != "" {
+				value = redacted
+			}
+			fmt.Fprintf(&sb, "  --%s = %s (%s)\n", name, value, opt.Source())
+		}
+	}
+
+	return sb.String()
+}
+
+// explain writes the resolved invocation to stderr, including global options, when --explain is set.
+func (cmd *Command) explain(cc *cobra.Command) {
+	if explain, err := cc.Flags().GetBool("explain"); err != nil || !explain {
+		return
+	}
+
+	out := cc.ErrOrStderr()
+	if _, err := io.WriteString(out, cmd.Explain()); err != nil {
+		cmd.log().Errorf("Could not explain invocation: %s", err)
+		return
+	}
+
+	if !cc.HasAvailableInheritedFlags() {
+		return
+	}
+
+	fmt.Fprintln(out, "Global options:")
+	rt := cmd.Runtime()
+	cc.InheritedFlags().VisitAll(func(flag *pflag.Flag) {
+		value, src := flag.Value.String(), Source{Kind: SourceDefault}
+		if flag.Changed {
+			src = Source{Kind: SourceFlag, Name: "--" + flag.Name}
+		} else if fromEnv, variable, ok := rt.FromEnv(flag.Name); ok {
+			value, src = fromEnv, Source{Kind: SourceEnv, Name: variable}
+		}
+		fmt.Fprintf(out, "  --%s = %s (%s)\n", flag.Name, value, src)
+	})
+}
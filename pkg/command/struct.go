@@ -0,0 +1,263 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package command
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// TagShortName sets the short name of an option defined from a struct field.
+	TagShortName = "short"
+	// TagDescription sets the description of an argument or option defined from a struct field.
+	TagDescription = "description"
+	// TagDefault sets the default of an argument or option defined from a struct field, lists are comma-separated.
+	TagDefault = "default"
+	// TagRequired marks an argument or option defined from a struct field as required.
+	TagRequired = "required"
+	// TagVariadic marks an argument defined from a slice field as variadic, which is the default for slices.
+	TagVariadic = "variadic"
+	// TagRepeated marks an option defined from a slice field as repeated, which is the default for slices.
+	TagRepeated = "repeated"
+	// TagValues sets the ValueSource of an argument or option defined from a struct field, in YAML flow style, i.e. `values:"{static: [a, b]}"`.
+	TagValues = "values"
+)
+
+// StructAction is the action of a command defined from a struct, receiving its decoded input.
+type StructAction[T any] func(ctx context.Context, cmd *Command, input *T) error
+
+// FromStruct returns cmd with its Arguments and Options defined by the tagged fields of T, and an action
+// that decodes parsed input into a new T before calling action. See Command.Decode.
+//
+//	type greet struct {
+//		Name  string `arg:"name" description:"who to greet" required:"true"`
+//		Shout bool   `option:"shout" short:"s" description:"greet loudly"`
+//	}
+//
+//	cmd, err := command.FromStruct(&command.Command{
+//		Path:        []string{"greet"},
+//		Summary:     "greets someone",
+//		Description: "says hello",
+//	}, func(ctx context.Context, cmd *command.Command, input *greet) error {
+//		// …
+//	})
+func FromStruct[T any](cmd *Command, action StructAction[T]) (*Command, error) {
+	var zero T
+	spec := reflect.TypeOf(zero)
+	if spec == nil || spec.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot define command %s from %T, a struct is required", cmd.FullName(), zero)
+	}
+
+	if cmd.Options == nil {
+		cmd.Options = Options{}
+	}
+
+	if err := defineFromStruct(cmd, spec); err != nil {
+		return nil, fmt.Errorf("cannot define command %s: %w", cmd.FullName(), err)
+	}
+
+	cmd.ContextAction = func(ctx context.Context, cmd *Command) error {
+		input := new(T)
+		if err := cmd.Decode(input); err != nil {
+			return err
+		}
+		return action(ctx, cmd, input)
+	}
+
+	return cmd.SetBindings(), nil
+}
+
+func defineFromStruct(cmd *Command, spec reflect.Type) error {
+	for idx := 0; idx < spec.NumField(); idx++ {
+		field := spec.Field(idx)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := defineFromStruct(cmd, field.Type); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if name, ok := field.Tag.Lookup(TagArgument); ok {
+			arg, err := argumentFromField(field, name)
+			if err != nil {
+				return err
+			}
+			cmd.Arguments = append(cmd.Arguments, arg)
+		} else if name, ok := field.Tag.Lookup(TagOption); ok {
+			name, opt, err := optionFromField(field, name)
+			if err != nil {
+				return err
+			}
+			if _, exists := cmd.Options[name]; exists {
+				return fmt.Errorf("option <%s> of field %s is already defined", name, field.Name)
+			}
+			cmd.Options[name] = opt
+		}
+	}
+
+	return nil
+}
+
+func argumentFromField(field reflect.StructField, name string) (*Argument, error) {
+	if name == "" {
+		name = kebabCase(field.Name)
+	}
+
+	fieldType := field.Type
+	arg := &Argument{
+		Name:        name,
+		Description: field.Tag.Get(TagDescription),
+		Variadic:    isList(fieldType),
+	}
+
+	if arg.Variadic {
+		fieldType = fieldType.Elem()
+	}
+	if fieldType.Kind() == reflect.Pointer {
+		fieldType = fieldType.Elem()
+	}
+	if arg.Type = typeOf(fieldType); arg.Type == ValueTypeDefault {
+		return nil, fmt.Errorf("argument <%s> of field %s has unsupported type %s", name, field.Name, field.Type)
+	}
+
+	var err error
+	if arg.Required, err = boolTag(field, TagRequired); err != nil {
+		return nil, err
+	}
+
+	if variadic, err := boolTag(field, TagVariadic); err != nil {
+		return nil, err
+	} else if variadic && !arg.Variadic {
+		return nil, fmt.Errorf("argument <%s> of field %s cannot be variadic, a slice is required", name, field.Name)
+	}
+
+	if def, ok := field.Tag.Lookup(TagDefault); ok {
+		if arg.Variadic {
+			arg.Default = strings.Split(def, ",")
+		} else {
+			arg.Default = def
+		}
+	}
+
+	if arg.Values, err = valuesTag(field); err != nil {
+		return nil, err
+	}
+
+	return arg, nil
+}
+
+func optionFromField(field reflect.StructField, name string) (string, *Option, error) {
+	if name == "" {
+		name = kebabCase(field.Name)
+	}
+
+	fieldType := field.Type
+	opt := &Option{
+		Description: field.Tag.Get(TagDescription),
+		ShortName:   field.Tag.Get(TagShortName),
+		Repeated:    isList(fieldType),
+	}
+
+	if repeated, err := boolTag(field, TagRepeated); err != nil {
+		return "", nil, err
+	} else if repeated && !opt.Repeated {
+		return "", nil, fmt.Errorf("option <%s> of field %s cannot be repeated, a slice is required", name, field.Name)
+	}
+
+	var err error
+	if opt.Required, err = boolTag(field, TagRequired); err != nil {
+		return "", nil, err
+	}
+
+	if opt.Repeated {
+		fieldType = fieldType.Elem()
+	}
+	if fieldType.Kind() == reflect.Pointer {
+		fieldType = fieldType.Elem()
+	}
+
+	opt.Type = typeOf(fieldType)
+	if opt.Type == ValueTypeDefault {
+		return "", nil, fmt.Errorf("option <%s> of field %s has unsupported type %s", name, field.Name, field.Type)
+	}
+
+	if def, ok := field.Tag.Lookup(TagDefault); ok {
+		switch {
+		case opt.Repeated:
+			defaults := strings.Split(def, ",")
+			for _, value := range defaults {
+				if _, err = opt.Type.Parse(value); err != nil {
+					break
+				}
+			}
+			opt.Default = defaults
+		case opt.Type == ValueTypeString:
+			opt.Default = def
+		default:
+			opt.Default, err = opt.Type.Parse(def)
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid default for option <%s> of field %s: %w", name, field.Name, err)
+		}
+	}
+
+	if opt.Values, err = valuesTag(field); err != nil {
+		return "", nil, err
+	}
+
+	return name, opt, nil
+}
+
+// isList tells if fields of type t hold many values, as opposed to types like net.IP that are slices themselves.
+func isList(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && typeOf(t) == ValueTypeDefault
+}
+
+func boolTag(field reflect.StructField, tag string) (bool, error) {
+	value, ok := field.Tag.Lookup(tag)
+	if !ok {
+		return false, nil
+	}
+
+	res, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s tag for field %s: %w", tag, field.Name, err)
+	}
+	return res, nil
+}
+
+func valuesTag(field reflect.StructField) (*ValueSource, error) {
+	spec, ok := field.Tag.Lookup(TagValues)
+	if !ok {
+		return nil, nil
+	}
+
+	vs := &ValueSource{}
+	if err := yaml.Unmarshal([]byte(spec), vs); err != nil {
+		return nil, fmt.Errorf("invalid values tag for field %s: %w", field.Name, err)
+	}
+	return vs, nil
+}
+
+// kebabCase turns a field name like SomeField into some-field.
+func kebabCase(name string) string {
+	var res strings.Builder
+	runes := []rune(name)
+	for idx, r := range runes {
+		if unicode.IsUpper(r) {
+			if idx > 0 && (unicode.IsLower(runes[idx-1]) || (idx+1 < len(runes) && unicode.IsLower(runes[idx+1]))) {
+				res.WriteRune('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		res.WriteRune(r)
+	}
+	return res.String()
+}
@@ -0,0 +1,156 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package command_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "git.rob.mx/nidito/chinampa/pkg/command"
+	"github.com/spf13/cobra"
+)
+
+type structInput struct {
+	Name    string   `arg:"name" description:"who to greet" required:"true" values:"{static: [alice, bob]}"`
+	Others  []string `arg:"" description:"others to greet"`
+	Shout   bool     `option:"shout" short:"s" description:"greet loudly"`
+	Times   int      `option:"times" description:"how many times" default:"1"`
+	Pause   int      `option:"pause" description:"seconds to wait between greetings"`
+	Salutes []string `option:"salute" description:"salutations to use" default:"hello,hi"`
+}
+
+func TestFromStruct(t *testing.T) {
+	var received *structInput
+	cmd, err := FromStruct(&Command{
+		Path:        []string{"test", "greet"},
+		Summary:     "greets",
+		Description: "greets someone",
+	}, func(ctx context.Context, cmd *Command, input *structInput) error {
+		received = input
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	static := []string{"alice", "bob"}
+	expected := &Command{
+		Path:        []string{"test", "greet"},
+		Summary:     "greets",
+		Description: "greets someone",
+		Arguments: Arguments{
+			{Name: "name", Description: "who to greet", Required: true},
+			{Name: "others", Description: "others to greet", Variadic: true},
+		},
+		Options: Options{
+			"shout":  {Type: ValueTypeBoolean, ShortName: "s", Description: "greet loudly"},
+			"times":  {Type: ValueTypeInt, Description: "how many times", Default: 1},
+			"pause":  {Type: ValueTypeInt, Description: "seconds to wait between greetings"},
+			"salute": {Type: ValueTypeString, Description: "salutations to use", Repeated: true, Default: []string{"hello", "hi"}},
+		},
+	}
+
+	for idx, arg := range cmd.Arguments {
+		exp := expected.Arguments[idx]
+		if arg.Name != exp.Name || arg.Description != exp.Description || arg.Required != exp.Required || arg.Variadic != exp.Variadic {
+			t.Fatalf("unexpected argument %d:\ngot   : %+v\nwanted: %+v", idx, arg, exp)
+		}
+	}
+
+	if values := cmd.Arguments[0].Values; values == nil || values.Static == nil || !reflect.DeepEqual(*values.Static, static) {
+		t.Fatalf("unexpected values for argument: %+v", values)
+	}
+
+	for name, exp := range expected.Options {
+		opt, ok := cmd.Options[name]
+		if !ok {
+			t.Fatalf("option %s not defined", name)
+		}
+		if opt.Type != exp.Type || opt.ShortName != exp.ShortName || opt.Description != exp.Description || opt.Repeated != exp.Repeated || !reflect.DeepEqual(opt.Default, exp.Default) {
+			t.Fatalf("unexpected option %s:\ngot   : %+v\nwanted: %+v", name, opt, exp)
+		}
+	}
+
+	if report := cmd.Validate(); len(report) > 0 {
+		t.Fatalf("defined command is invalid: %v", report)
+	}
+
+	cc := &cobra.Command{}
+	cc.Flags().AddFlagSet(cmd.FlagSet())
+	if err := cc.Flags().Parse([]string{"-s", "--salute", "hey"}); err != nil {
+		t.Fatalf("could not parse flags: %s", err)
+	}
+	if err := cmd.Run(cc, []string{"bob", "carol"}); err != nil {
+		t.Fatalf("unexpected error running: %s", err)
+	}
+
+	expectedInput := &structInput{Name: "bob", Others: []string{"carol"}, Shout: true, Times: 1, Salutes: []string{"hey"}}
+	if !reflect.DeepEqual(received, expectedInput) {
+		t.Fatalf("unexpected input:\ngot   : %+v\nwanted: %+v", received, expectedInput)
+	}
+}
+
+func TestFromStructErrors(t *testing.T) {
+	cases := []struct {
+		Name  string
+		Error string
+		Fn    func() error
+	}{
+		{
+			Name:  "non-struct",
+			Error: "cannot define command test from string, a struct is required",
+			Fn: func() error {
+				_, err := FromStruct(&Command{Path: []string{"test"}}, func(ctx context.Context, cmd *Command, input *string) error { return nil })
+				return err
+			},
+		},
+		{
+			Name:  "bad variadic",
+			Error: "argument <name> of field Name cannot be variadic",
+			Fn: func() error {
+				type spec struct {
+					Name string `arg:"name" variadic:"true"`
+				}
+				_, err := FromStruct(&Command{Path: []string{"test"}}, func(ctx context.Context, cmd *Command, input *spec) error { return nil })
+				return err
+			},
+		},
+		{
+			Name:  "bad default",
+			Error: "invalid default for option <count> of field Count",
+			Fn: func() error {
+				type spec struct {
+					Count int `option:"count" default:"many"`
+				}
+				_, err := FromStruct(&Command{Path: []string{"test"}}, func(ctx context.Context, cmd *Command, input *spec) error { return nil })
+				return err
+			},
+		},
+		{
+			Name:  "unsupported type",
+			Error: "option <nested> of field Nested has unsupported type struct {}",
+			Fn: func() error {
+				type spec struct {
+					Nested struct{} `option:"nested"`
+				}
+				_, err := FromStruct(&Command{Path: []string{"test"}}, func(ctx context.Context, cmd *Command, input *spec) error { return nil })
+				return err
+			},
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			err := c.Fn()
+			if err == nil {
+				t.Fatalf("expected error, got none")
+			}
+			if !strings.Contains(err.Error(), c.Error) {
+				t.Fatalf("could not find error <%s> got <%s>", c.Error, err)
+			}
+		})
+	}
+}
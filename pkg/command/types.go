@@ -0,0 +1,380 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package command
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DateLayout is the layout accepted for ValueTypeTime values, besides time.RFC3339.
+const DateLayout = time.DateOnly
+
+// ByteSize is an amount of bytes.
+type ByteSize int64
+
+var byteSizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1e3,
+	"kb":  1e3,
+	"m":   1e6,
+	"mb":  1e6,
+	"g":   1e9,
+	"gb":  1e9,
+	"t":   1e12,
+	"tb":  1e12,
+	"p":   1e15,
+	"pb":  1e15,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+	"pib": 1 << 50,
+}
+
+var byteSizeSuffixes = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}
+
+// ParseByteSize parses amounts of bytes with SI (KB, MB, …) or binary (KiB, MiB, …) units, like 10MiB or 1.5GB.
+func ParseByteSize(raw string) (ByteSize, error) {
+	trimmed := strings.TrimSpace(raw)
+	split := strings.IndexFunc(trimmed, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.'
+	})
+	if split == -1 {
+		split = len(trimmed)
+	}
+
+	amount, err := strconv.ParseFloat(trimmed[0:split], 64)
+	if err != nil || amount < 0 {
+		return 0, fmt.Errorf("invalid byte size %q", raw)
+	}
+
+	multiplier, ok := byteSizeUnits[strings.ToLower(strings.TrimSpace(trimmed[split:]))]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit in byte size %q", raw)
+	}
+
+	total := amount * multiplier
+	if total > math.MaxInt64 {
+		return 0, fmt.Errorf("byte size %q is too large", raw)
+	}
+
+	return ByteSize(total), nil
+}
+
+// String formats a ByteSize with the largest binary unit that represents it exactly.
+func (bs ByteSize) String() string {
+	value := int64(bs)
+	unit := 0
+	for value != 0 && value%1024 == 0 && unit < len(byteSizeSuffixes)-1 {
+		value /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%d%s", value, byteSizeSuffixes[unit])
+}
+
+// IsTyped tells if values of this type are represented by something other than a string, bool, or int.
+func (vt ValueType) IsTyped() bool {
+	switch vt {
+	case ValueTypeDefault, ValueTypeString, ValueTypeBoolean, ValueTypeInt, ValueTypeCount:
+		return false
+	}
+	return true
+}
+
+// Parse returns the typed value for the string raw.
+func (vt ValueType) Parse(raw string) (any, error) {
+	switch vt {
+	case ValueTypeDefault, ValueTypeString:
+		return raw, nil
+	case ValueTypeBoolean:
+		return strconv.ParseBool(raw)
+	case ValueTypeInt, ValueTypeCount:
+		return strconv.Atoi(raw)
+	case ValueTypeFloat:
+		return strconv.ParseFloat(raw, 64)
+	case ValueTypeDuration:
+		return time.ParseDuration(raw)
+	case ValueTypeTime:
+		if parsed, err := time.Parse(time.RFC3339, raw); err == nil {
+			return parsed, nil
+		}
+		parsed, err := time.ParseInLocation(DateLayout, raw, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time %q, expected RFC3339 or %s", raw, DateLayout)
+		}
+		return parsed, nil
+	case ValueTypeURL:
+		parsed, err := url.Parse(raw)
+		if err != nil {
+			return nil, err
+		}
+		if parsed.Scheme == "" {
+			return nil, fmt.Errorf("invalid url %q, a scheme is required", raw)
+		}
+		return parsed, nil
+	case ValueTypeIP:
+		parsed := net.ParseIP(raw)
+		if parsed == nil {
+			return nil, fmt.Errorf("invalid ip address %q", raw)
+		}
+		return parsed, nil
+	case ValueTypeCIDR:
+		_, parsed, err := net.ParseCIDR(raw)
+		if err != nil {
+			return nil, err
+		}
+		return parsed, nil
+	case ValueTypeByteSize:
+		return ParseByteSize(raw)
+	case ValueTypeMap:
+		res := map[string]string{}
+		if raw == "" {
+			return res, nil
+		}
+		for _, pair := range strings.Split(raw, ",") {
+			key, value, found := strings.Cut(pair, "=")
+			if !found {
+				return nil, fmt.Errorf("invalid key=value pair %q", pair)
+			}
+			res[key] = value
+		}
+		return res, nil
+	}
+
+	return nil, fmt.Errorf("unknown value type <%s>", vt)
+}
+
+// Format returns the string representation of value.
+func (vt ValueType) Format(value any) string {
+	if value == nil {
+		return ""
+	}
+
+	switch val := value.(type) {
+	case string:
+		return val
+	case time.Time:
+		return val.Format(time.RFC3339)
+	case map[string]string:
+		pairs := make([]string, 0, len(val))
+		for k, v := range val {
+			pairs = append(pairs, k+"="+v)
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, ",")
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case fmt.Stringer:
+		if rv := reflect.ValueOf(val); rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return ""
+		}
+		return val.String()
+	}
+
+	if reflect.ValueOf(value).Kind() == reflect.Slice {
+		return strings.Join(formatEach(vt, value), ",")
+	}
+
+	return fmt.Sprint(value)
+}
+
+// formatEach returns the string representation of every item in the slice values.
+func formatEach(vt ValueType, values any) []string {
+	rv := reflect.ValueOf(values)
+	if rv.Kind() != reflect.Slice {
+		return []string{}
+	}
+
+	res := make([]string, rv.Len())
+	for idx := range res {
+		res[idx] = vt.Format(rv.Index(idx).Interface())
+	}
+	return res
+}
+
+// coerce returns value as the type represented by vt, parsing it when given a string.
+func (vt ValueType) coerce(value any) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	if str, ok := value.(string); ok {
+		return vt.Parse(str)
+	}
+
+	if vt == ValueTypeMap {
+		if src, ok := value.(map[string]any); ok {
+			res := map[string]string{}
+			for k, v := range src {
+				res[k] = fmt.Sprint(v)
+			}
+			return res, nil
+		}
+	}
+
+	if vt == ValueTypeFloat {
+		switch val := value.(type) {
+		case int:
+			return float64(val), nil
+		case float32:
+			return float64(val), nil
+		}
+	}
+
+	if vt == ValueTypeTime {
+		if val, ok := value.(time.Time); ok {
+			return val, nil
+		}
+	}
+
+	parsed, err := vt.Parse(vt.Format(value))
+	if err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
+
+// typeOf returns the ValueType for values of the go type t, or ValueTypeDefault for unsupported ones.
+func typeOf(t reflect.Type) ValueType {
+	switch t {
+	case reflect.TypeOf(time.Duration(0)):
+		return ValueTypeDuration
+	case reflect.TypeOf(time.Time{}):
+		return ValueTypeTime
+	case reflect.TypeOf(url.URL{}), reflect.TypeOf(&url.URL{}):
+		return ValueTypeURL
+	case reflect.TypeOf(net.IP{}):
+		return ValueTypeIP
+	case reflect.TypeOf(net.IPNet{}), reflect.TypeOf(&net.IPNet{}):
+		return ValueTypeCIDR
+	case reflect.TypeOf(ByteSize(0)):
+		return ValueTypeByteSize
+	case reflect.TypeOf(map[string]string{}):
+		return ValueTypeMap
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return ValueTypeString
+	case reflect.Bool:
+		return ValueTypeBoolean
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return ValueTypeInt
+	case reflect.Float32, reflect.Float64:
+		return ValueTypeFloat
+	}
+	return ValueTypeDefault
+}
+
+// countIncrement is the value pflag sets count flags to when provided without one.
+const countIncrement = "+1"
+
+// typedFlag is a pflag.Value for ValueTypes not natively supported by pflag.
+type typedFlag struct {
+	kind     ValueType
+	repeated bool
+	value    any
+	changed  bool
+}
+
+func newTypedFlag(kind ValueType, repeated bool, def any) (*typedFlag, error) {
+	flag := &typedFlag{kind: kind, repeated: repeated}
+	if kind == ValueTypeCount {
+		flag.value = 0
+	}
+
+	if def == nil {
+		return flag, nil
+	}
+
+	if !repeated {
+		value, err := kind.coerce(def)
+		flag.value = value
+		return flag, err
+	}
+
+	defaults := reflect.ValueOf(def)
+	if defaults.Kind() != reflect.Slice {
+		defaults = reflect.ValueOf([]any{def})
+	}
+	for idx := 0; idx < defaults.Len(); idx++ {
+		value, err := kind.coerce(defaults.Index(idx).Interface())
+		if err != nil {
+			return flag, err
+		}
+		flag.append(value)
+	}
+	return flag, nil
+}
+
+func (tf *typedFlag) append(value any) {
+	if tf.value == nil {
+		tf.value = reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(value)), 0, 1).Interface()
+	}
+	tf.value = reflect.Append(reflect.ValueOf(tf.value), reflect.ValueOf(value)).Interface()
+}
+
+func (tf *typedFlag) String() string {
+	return tf.kind.Format(tf.value)
+}
+
+func (tf *typedFlag) Set(raw string) error {
+	if tf.kind == ValueTypeCount && (raw == countIncrement || raw == "true" || raw == "false") {
+		if !tf.changed {
+			tf.value = 0
+			tf.changed = true
+		}
+
+		// --verbose=true counts like --verbose, while --verbose=false resets the count
+		if raw == "false" {
+			tf.value = 0
+		} else {
+			tf.value = tf.value.(int) + 1
+		}
+		return nil
+	}
+
+	value, err := tf.kind.Parse(raw)
+	if err != nil {
+		return err
+	}
+
+	if !tf.changed {
+		// user-supplied values replace defaults
+		tf.value = nil
+		tf.changed = true
+	}
+
+	switch {
+	case tf.kind == ValueTypeMap:
+		if tf.value == nil {
+			tf.value = map[string]string{}
+		}
+		for k, v := range value.(map[string]string) {
+			tf.value.(map[string]string)[k] = v
+		}
+	case tf.repeated:
+		tf.append(value)
+	default:
+		tf.value = value
+	}
+	return nil
+}
+
+func (tf *typedFlag) Type() string {
+	if tf.repeated {
+		return string(tf.kind) + "Array"
+	}
+	return string(tf.kind)
+}
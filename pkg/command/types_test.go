@@ -0,0 +1,195 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package command_test
+
+import (
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	. "git.rob.mx/nidito/chinampa/pkg/command"
+)
+
+func TestValueTypeParse(t *testing.T) {
+	mustURL, _ := url.Parse("https://example.com/path?q=1")
+	_, cidr, _ := net.ParseCIDR("10.0.0.0/8")
+
+	cases := []struct {
+		Type      ValueType
+		Raw       string
+		Expected  any
+		Formatted string
+		Error     string
+	}{
+		{Type: ValueTypeFloat, Raw: "1.5", Expected: 1.5, Formatted: "1.5"},
+		{Type: ValueTypeFloat, Raw: "one", Error: "invalid syntax"},
+		{Type: ValueTypeDuration, Raw: "1h30m", Expected: 90 * time.Minute, Formatted: "1h30m0s"},
+		{Type: ValueTypeDuration, Raw: "soon", Error: "invalid duration"},
+		{Type: ValueTypeTime, Raw: "2024-02-03T04:05:06Z", Expected: time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC), Formatted: "2024-02-03T04:05:06Z"},
+		{Type: ValueTypeTime, Raw: "2024-02-03", Expected: time.Date(2024, 2, 3, 0, 0, 0, 0, time.Local), Formatted: time.Date(2024, 2, 3, 0, 0, 0, 0, time.Local).Format(time.RFC3339)},
+		{Type: ValueTypeTime, Raw: "yesterday", Error: "invalid time"},
+		{Type: ValueTypeURL, Raw: "https://example.com/path?q=1", Expected: mustURL, Formatted: "https://example.com/path?q=1"},
+		{Type: ValueTypeURL, Raw: "example.com", Error: "a scheme is required"},
+		{Type: ValueTypeIP, Raw: "::1", Expected: net.ParseIP("::1"), Formatted: "::1"},
+		{Type: ValueTypeIP, Raw: "256.0.0.1", Error: "invalid ip address"},
+		{Type: ValueTypeCIDR, Raw: "10.0.0.0/8", Expected: cidr, Formatted: "10.0.0.0/8"},
+		{Type: ValueTypeCIDR, Raw: "10.0.0.0", Error: "invalid CIDR address"},
+		{Type: ValueTypeByteSize, Raw: "10MiB", Expected: ByteSize(10 << 20), Formatted: "10MiB"},
+		{Type: ValueTypeByteSize, Raw: "1.5 KB", Expected: ByteSize(1500), Formatted: "1500B"},
+		{Type: ValueTypeByteSize, Raw: "2048", Expected: ByteSize(2048), Formatted: "2KiB"},
+		{Type: ValueTypeByteSize, Raw: "10XB", Error: "unknown unit"},
+		{Type: ValueTypeMap, Raw: "b=2,a=1", Expected: map[string]string{"a": "1", "b": "2"}, Formatted: "a=1,b=2"},
+		{Type: ValueTypeMap, Raw: "a", Error: "invalid key=value pair"},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(string(c.Type)+"/"+c.Raw, func(t *testing.T) {
+			res, err := c.Type.Parse(c.Raw)
+			if c.Error != "" {
+				if err == nil || !strings.Contains(err.Error(), c.Error) {
+					t.Fatalf("expected error <%s>, got <%v>", c.Error, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if !reflect.DeepEqual(res, c.Expected) {
+				t.Fatalf("unexpected value:\ngot   : %#v\nwanted: %#v", res, c.Expected)
+			}
+
+			if formatted := c.Type.Format(res); formatted != c.Formatted {
+				t.Fatalf("unexpected format: got %s, wanted %s", formatted, c.Formatted)
+			}
+		})
+	}
+}
+
+func TestTypedOptions(t *testing.T) {
+	cmd := (&Command{
+		Path: []string{"test", "typed"},
+		Options: Options{
+			"timeout": {Type: ValueTypeDuration, Default: "5s"},
+			"ratio":   {Type: ValueTypeFloat, Default: 1},
+			"size":    {Type: ValueTypeByteSize},
+			"label":   {Type: ValueTypeMap, Repeated: true},
+			"ip":      {Type: ValueTypeIP, Repeated: true, Default: []any{"127.0.0.1"}},
+			"count":   {Type: ValueTypeInt, Repeated: true},
+		},
+	}).SetBindings()
+
+	fs := cmd.FlagSet()
+	err := fs.Parse([]string{"--size", "1GiB", "--label", "a=1", "--label", "b=2,c=3", "--ip", "::1", "--ip", "10.0.0.1", "--count", "1", "--count", "2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	cmd.Options.Parse(fs)
+
+	expected := map[string]any{
+		"timeout": 5 * time.Second,
+		"ratio":   float64(1),
+		"size":    ByteSize(1 << 30),
+		"label":   map[string]string{"a": "1", "b": "2", "c": "3"},
+		"ip":      []net.IP{net.ParseIP("::1"), net.ParseIP("10.0.0.1")},
+		"count":   []int{1, 2},
+	}
+	if known := cmd.Options.AllKnown(); !reflect.DeepEqual(known, expected) {
+		t.Fatalf("unexpected values:\ngot   : %#v\nwanted: %#v", known, expected)
+	}
+
+	expectedStr := map[string]string{
+		"timeout": "5s",
+		"ratio":   "1",
+		"size":    "1GiB",
+		"label":   "a=1,b=2,c=3",
+		"ip":      "::1,10.0.0.1",
+		"count":   "1,2",
+	}
+	if known := cmd.Options.AllKnownStr(); !reflect.DeepEqual(known, expectedStr) {
+		t.Fatalf("unexpected strings:\ngot   : %#v\nwanted: %#v", known, expectedStr)
+	}
+
+	if err := fs.Parse([]string{"--timeout", "never"}); err == nil {
+		t.Fatalf("invalid duration did not fail")
+	}
+}
+
+func TestTypedArguments(t *testing.T) {
+	cmd := (&Command{
+		Path: []string{"test", "typed"},
+		Arguments: Arguments{
+			{Name: "count", Type: ValueTypeInt, Default: 3},
+			{Name: "sizes", Type: ValueTypeByteSize, Variadic: true},
+		},
+	}).SetBindings()
+
+	if val := cmd.Arguments[0].ToValue(); val != 3 {
+		t.Fatalf("unexpected default: %#v", val)
+	}
+
+	if err := cmd.Arguments.Parse([]string{"10", "1KiB", "1MB"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := map[string]any{
+		"count": 10,
+		"sizes": []ByteSize{1024, 1000000},
+	}
+	if known := cmd.Arguments.AllKnown(); !reflect.DeepEqual(known, expected) {
+		t.Fatalf("unexpected values:\ngot   : %#v\nwanted: %#v", known, expected)
+	}
+
+	if sizes := cmd.Arguments[1].ToString(); sizes != "1KiB 1MB" {
+		t.Fatalf("unexpected string: %s", sizes)
+	}
+
+	err := cmd.Arguments.Parse([]string{"ten"})
+	if err == nil || err.Error() != "ten is not a valid int for argument <count>: strconv.Atoi: parsing \"ten\": invalid syntax" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCountOption(t *testing.T) {
+	cases := []struct {
+		Args    []string
+		Default any
+		Expects int
+	}{
+		{Args: []string{}, Expects: 0},
+		{Args: []string{}, Default: 2, Expects: 2},
+		{Args: []string{"-vvv"}, Expects: 3},
+		{Args: []string{"-v", "--verbose"}, Default: 2, Expects: 2},
+		{Args: []string{"--verbose=5"}, Expects: 5},
+		{Args: []string{"--verbose=true"}, Expects: 1},
+		{Args: []string{"-vv", "--verbose=true"}, Expects: 3},
+		{Args: []string{"-vv", "--verbose=false"}, Default: 2, Expects: 0},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(strings.Join(c.Args, " "), func(t *testing.T) {
+			cmd := (&Command{
+				Path: []string{"test", "count"},
+				Options: Options{
+					"verbose": {Type: ValueTypeCount, ShortName: "v", Default: c.Default},
+				},
+			}).SetBindings()
+
+			fs := cmd.FlagSet()
+			if err := fs.Parse(c.Args); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			cmd.Options.Parse(fs)
+
+			if res := cmd.Options["verbose"].ToValue(); res != c.Expects {
+				t.Fatalf("got %v, wanted %d", res, c.Expects)
+			}
+		})
+	}
+}
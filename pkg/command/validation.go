@@ -27,12 +27,47 @@ func (cmd *Command) Validate() (report map[string]int) {
 		}
 	}
 
+	for idx, arg := range cmd.Arguments {
+		if arg == nil {
+			report[fmt.Sprintf("argument #%d is empty", idx)] = 1
+			continue
+		}
+
+		if arg.Validation != nil {
+			for _, problem := range arg.Validation.problems(arg.Type) {
+				report[fmt.Sprintf("argument <%s> validation: %s", arg.Name, problem)] = 1
+			}
+		}
+	}
+
+	for name, opt := range cmd.Options {
+		if opt == nil {
+			report[fmt.Sprintf("option <%s> is empty", name)] = 1
+			continue
+		}
+
+		if opt.Validation != nil {
+			for _, problem := range opt.Validation.problems(opt.Type) {
+				report[fmt.Sprintf("option <%s> validation: %s", name, problem)] = 1
+			}
+		}
+	}
+
+	for _, name := range cmd.Constraints.names() {
+		if _, isOption := cmd.Options[name]; !isOption && cmd.Arguments.Get(name) == nil {
+			report[fmt.Sprintf("constraints reference unknown option or argument <%s>", name)] = 1
+		}
+	}
+
 	vars := map[string]map[string]*varSearchMap{
 		"argument": {},
 		"option":   {},
 	}
 
 	for _, arg := range cmd.Arguments {
+		if arg == nil {
+			continue
+		}
 		vars["argument"][strings.ToUpper(strings.ReplaceAll(arg.Name, "-", "_"))] = &varSearchMap{2, arg.Name, ""}
 	}
 
@@ -0,0 +1,237 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package command
+
+import (
+	stderrors "errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"unicode/utf8"
+
+	"git.rob.mx/nidito/chinampa/pkg/errors"
+)
+
+// ValidatorFunc checks value, typed after its argument or option, returning an error explaining why it is invalid.
+type ValidatorFunc func(value any) error
+
+// InputValidator checks the parsed arguments and options of cmd, i.e. to compare their values.
+type InputValidator func(cmd *Command) error
+
+// Validation holds checks run against each user-supplied value of an argument or option, in addition to
+// those provided by its ValueSource. Values resolved from defaults are not checked.
+type Validation struct {
+	// Min is the smallest value allowed for int, float, count and bytesize values
+	Min *float64 `json:"min,omitempty" yaml:"min,omitempty"`
+	// Max is the largest value allowed for int, float, count and bytesize values
+	Max *float64 `json:"max,omitempty" yaml:"max,omitempty"`
+	// Pattern is a regular expression values must match
+	Pattern string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	// MinLength is the least amount of characters allowed
+	MinLength *int `json:"min-length,omitempty" yaml:"min-length,omitempty"` // nolint:tagliatelle
+	// MaxLength is the most amount of characters allowed
+	MaxLength *int `json:"max-length,omitempty" yaml:"max-length,omitempty"` // nolint:tagliatelle
+	// FileExists requires values to be paths to existing files or directories
+	FileExists bool `json:"file-exists,omitempty" yaml:"file-exists,omitempty"` // nolint:tagliatelle
+	// IsDir requires values to be paths to existing directories
+	IsDir bool `json:"is-dir,omitempty" yaml:"is-dir,omitempty"` // nolint:tagliatelle
+	// IsWritable requires values to be paths the current user may write to, or create
+	IsWritable bool `json:"is-writable,omitempty" yaml:"is-writable,omitempty"` // nolint:tagliatelle
+	// Func is called with each value
+	Func    ValidatorFunc `json:"-" yaml:"-"`
+	pattern *regexp.Regexp
+}
+
+// clone returns a copy of v, so compiling its pattern does not affect v.
+func (v *Validation) clone() *Validation {
+	if v == nil {
+		return nil
+	}
+	clone := *v
+	return &clone
+}
+
+// Check returns an error explaining why raw, a string representation of a value of type kind, is invalid.
+func (v *Validation) Check(kind ValueType, raw string) error {
+	value, err := kind.Parse(raw)
+	if err != nil {
+		return err
+	}
+
+	if v.Min != nil || v.Max != nil {
+		number, ok := toFloat(value)
+		if !ok {
+			return fmt.Errorf("%s values cannot be compared to a range", kind)
+		}
+
+		if v.Min != nil && number < *v.Min {
+			return fmt.Errorf("must be at least %v", *v.Min)
+		}
+
+		if v.Max != nil && number > *v.Max {
+			return fmt.Errorf("must be at most %v", *v.Max)
+		}
+	}
+
+	if length := utf8.RuneCountInString(raw); v.MinLength != nil && length < *v.MinLength {
+		return fmt.Errorf("must be at least %d characters long", *v.MinLength)
+	} else if v.MaxLength != nil && length > *v.MaxLength {
+		return fmt.Errorf("must be at most %d characters long", *v.MaxLength)
+	}
+
+	if v.Pattern != "" {
+		if v.pattern == nil {
+			if v.pattern, err = regexp.Compile(v.Pattern); err != nil {
+				return fmt.Errorf("invalid pattern %s: %w", v.Pattern, err)
+			}
+		}
+
+		if !v.pattern.MatchString(raw) {
+			return fmt.Errorf("must match %s", v.Pattern)
+		}
+	}
+
+	if err := v.checkPath(raw); err != nil {
+		return err
+	}
+
+	if v.Func != nil {
+		return v.Func(value)
+	}
+
+	return nil
+}
+
+// problems returns issues with this Validation's definition for values of type kind.
+func (v *Validation) problems(kind ValueType) []string {
+	if kind == ValueTypeDefault {
+		kind = ValueTypeString
+	}
+
+	problems := []string{}
+	if v.Min != nil || v.Max != nil {
+		switch kind {
+		case ValueTypeInt, ValueTypeFloat, ValueTypeCount, ValueTypeByteSize:
+		default:
+			problems = append(problems, fmt.Sprintf("min and max cannot be used with %s values", kind))
+		}
+	}
+
+	if v.Pattern != "" {
+		if _, err := regexp.Compile(v.Pattern); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid pattern %s: %s", v.Pattern, err))
+		}
+	}
+
+	return problems
+}
+
+func (v *Validation) checkPath(path string) error {
+	if !v.FileExists && !v.IsDir && !v.IsWritable {
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+
+		if v.FileExists || v.IsDir {
+			return fmt.Errorf("no such file or directory")
+		}
+
+		// writable paths that don't exist yet must be creatable
+		return writable(filepath.Dir(path), true)
+	}
+
+	if v.IsDir && !info.IsDir() {
+		return fmt.Errorf("not a directory")
+	}
+
+	if v.IsWritable {
+		return writable(path, info.IsDir())
+	}
+
+	return nil
+}
+
+func writable(path string, isDir bool) error {
+	if !isDir {
+		fd, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return fmt.Errorf("not writable")
+		}
+		return fd.Close()
+	}
+
+	fd, err := os.CreateTemp(path, ".chinampa-writable-*")
+	if err != nil {
+		return fmt.Errorf("directory is not writable")
+	}
+	fd.Close() // nolint: errcheck
+	return os.Remove(fd.Name())
+}
+
+func toFloat(value any) (float64, bool) {
+	switch number := value.(type) {
+	case int:
+		return float64(number), true
+	case float64:
+		return number, true
+	case ByteSize:
+		return float64(number), true
+	}
+	return 0, false
+}
+
+// validateEach runs validation for every value, returning copies of offender, an errors.BadArguments naming the
+// argument or option, for subject, i.e. option <name>.
+func validateEach(validation *Validation, kind ValueType, values []string, subject string, offender errors.BadArguments) error {
+	if validation == nil {
+		return nil
+	}
+
+	problems := []error{}
+	for _, value := range values {
+		if err := validation.Check(kind, value); err != nil {
+			offender.Msg = fmt.Sprintf("%s is not a valid value for %s: %s", value, subject, err)
+			problems = append(problems, offender)
+		}
+	}
+	return errors.Combine(problems...)
+}
+
+// validateInput runs cmd's InputValidator, if any.
+func (cmd *Command) validateInput() error {
+	if cmd.ValidateInput == nil {
+		return nil
+	}
+
+	err := cmd.ValidateInput(cmd)
+	if err == nil {
+		return nil
+	}
+
+	return usageError(err)
+}
+
+// usageError returns err, as returned by an InputValidator, as a usage error: errors wrapping a BadArguments are
+// returned as they are, and those combined in a Multiple are converted one by one.
+func usageError(err error) error {
+	var multiple errors.Multiple
+	if stderrors.As(err, &multiple) {
+		problems := make([]error, len(multiple.Errors))
+		for idx, problem := range multiple.Errors {
+			problems[idx] = usageError(problem)
+		}
+		return errors.Multiple{Errors: problems}
+	}
+
+	var bad errors.BadArguments
+	if stderrors.As(err, &bad) {
+		return err
+	}
+	return errors.BadArguments{Msg: err.Error()}
+}
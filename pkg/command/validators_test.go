@@ -0,0 +1,218 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package command_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "git.rob.mx/nidito/chinampa/pkg/command"
+	"git.rob.mx/nidito/chinampa/pkg/errors"
+	"git.rob.mx/nidito/chinampa/pkg/statuscode"
+	"github.com/spf13/cobra"
+)
+
+func TestValidationCheck(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, []byte{}, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	one := 1.0
+	ten := 10.0
+	two := 2
+	four := 4
+
+	cases := []struct {
+		Name       string
+		Validation *Validation
+		Kind       ValueType
+		Value      string
+		Error      string
+	}{
+		{Name: "in range", Validation: &Validation{Min: &one, Max: &ten}, Kind: ValueTypeInt, Value: "5"},
+		{Name: "below min", Validation: &Validation{Min: &one}, Kind: ValueTypeInt, Value: "0", Error: "must be at least 1"},
+		{Name: "above max", Validation: &Validation{Max: &ten}, Kind: ValueTypeFloat, Value: "10.5", Error: "must be at most 10"},
+		{Name: "bytesize range", Validation: &Validation{Max: &ten}, Kind: ValueTypeByteSize, Value: "1KiB", Error: "must be at most 10"},
+		{Name: "not a number", Validation: &Validation{Max: &ten}, Kind: ValueTypeString, Value: "a", Error: "string values cannot be compared to a range"},
+		{Name: "short", Validation: &Validation{MinLength: &two}, Value: "a", Error: "must be at least 2 characters long"},
+		{Name: "long", Validation: &Validation{MaxLength: &four}, Value: "ñañañ", Error: "must be at most 4 characters long"},
+		{Name: "matches", Validation: &Validation{Pattern: "^[a-z]+$"}, Value: "abc"},
+		{Name: "does not match", Validation: &Validation{Pattern: "^[a-z]+$"}, Value: "ABC", Error: "must match ^[a-z]+$"},
+		{Name: "exists", Validation: &Validation{FileExists: true}, Value: file},
+		{Name: "missing", Validation: &Validation{FileExists: true}, Value: file + "-missing", Error: "no such file or directory"},
+		{Name: "is dir", Validation: &Validation{IsDir: true}, Value: dir},
+		{Name: "not a dir", Validation: &Validation{IsDir: true}, Value: file, Error: "not a directory"},
+		{Name: "writable file", Validation: &Validation{IsWritable: true}, Value: file},
+		{Name: "writable new file", Validation: &Validation{IsWritable: true}, Value: filepath.Join(dir, "new")},
+		{Name: "unwritable new file", Validation: &Validation{IsWritable: true}, Value: filepath.Join(dir, "missing", "new"), Error: "directory is not writable"},
+		{
+			Name:  "func",
+			Kind:  ValueTypeInt,
+			Value: "3",
+			Validation: &Validation{Func: func(value any) error {
+				if value.(int)%2 != 0 {
+					return fmt.Errorf("must be even")
+				}
+				return nil
+			}},
+			Error: "must be even",
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			err := c.Validation.Check(c.Kind, c.Value)
+			if c.Error == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				return
+			}
+
+			if err == nil || err.Error() != c.Error {
+				t.Fatalf("expected error <%s>, got <%v>", c.Error, err)
+			}
+		})
+	}
+}
+
+func TestValidatorsOnParse(t *testing.T) {
+	three := 3
+	max := 5.0
+	newCmd := func() *Command {
+		return (&Command{
+			Path: []string{"test", "validators"},
+			Arguments: Arguments{
+				{Name: "name", Validation: &Validation{MinLength: &three}},
+			},
+			Options: Options{
+				"replicas": {Type: ValueTypeInt, Default: 10, Validation: &Validation{Max: &max}},
+				"min":      {Type: ValueTypeInt},
+				"max":      {Type: ValueTypeInt},
+			},
+			ValidateInput: func(cmd *Command) error {
+				opts := cmd.Options.AllKnown()
+				if opts["min"].(int) > opts["max"].(int) {
+					return fmt.Errorf("--min cannot be greater than --max")
+				}
+				return nil
+			},
+		}).SetBindings()
+	}
+
+	cases := []struct {
+		Name  string
+		Args  []string
+		Error string
+	}{
+		{Name: "defaults are not validated", Args: []string{"abc"}},
+		{Name: "argument", Args: []string{"ab"}, Error: "ab is not a valid value for argument <name>: must be at least 3 characters long"},
+		{Name: "option", Args: []string{"--replicas", "6", "abc"}, Error: "6 is not a valid value for option <replicas>: must be at most 5"},
+		{
+			Name: "every value",
+			Args: []string{"--replicas", "6", "ab"},
+			Error: `Found 2 problems:
+  - ab is not a valid value for argument <name>: must be at least 3 characters long
+  - 6 is not a valid value for option <replicas>: must be at most 5`,
+		},
+		{Name: "command", Args: []string{"--min", "2", "--max", "1", "abc"}, Error: "--min cannot be greater than --max"},
+		{Name: "skipped", Args: []string{"--skip-validation", "--replicas", "6", "ab"}},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			cmd := newCmd()
+			cc := &cobra.Command{Use: "validators"}
+			cc.Flags().AddFlagSet(cmd.FlagSet())
+			cc.Flags().Bool("skip-validation", false, "skip validation")
+			if err := cc.Flags().Parse(c.Args); err != nil {
+				t.Fatal(err)
+			}
+
+			err := cmd.ParseInput(cc, cc.Flags().Args())
+			if c.Error == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				return
+			}
+
+			if err == nil || err.Error() != c.Error {
+				t.Fatalf("expected error <%s>, got <%v>", c.Error, err)
+			}
+		})
+	}
+
+	cmd := newCmd()
+	cmd.Options["min"].Validation = &Validation{Pattern: "("}
+	cmd.Arguments[0].Validation.Max = &max
+	report := cmd.Validate()
+	for _, expected := range []string{"option <min> validation: invalid pattern (", "argument <name> validation: min and max cannot be used with string values"} {
+		found := false
+		for issue := range report {
+			found = found || strings.HasPrefix(issue, expected)
+		}
+		if !found {
+			t.Fatalf("expected validation issue <%s>, got %v", expected, report)
+		}
+	}
+}
+
+func TestValidateInputErrors(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Err      error
+		Option   string
+		Problems []errors.Problem
+	}{
+		{
+			Name: "plain",
+			Err:  fmt.Errorf("--min cannot be greater than --max"),
+		},
+		{
+			Name:   "wrapped",
+			Err:    fmt.Errorf("comparing: %w", errors.BadArguments{Msg: "--min cannot be greater than --max", Option: "min"}),
+			Option: "min",
+		},
+		{
+			Name: "multiple",
+			Err:  errors.Multiple{Errors: []error{fmt.Errorf("too small"), errors.BadArguments{Msg: "too big", Option: "max"}}},
+			Problems: []errors.Problem{
+				{Message: "too small"},
+				{Message: "too big", Option: "max"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			cmd := (&Command{
+				Path:          []string{"test", "validators"},
+				Options:       Options{"min": {Type: ValueTypeInt}, "max": {Type: ValueTypeInt}},
+				ValidateInput: func(cmd *Command) error { return c.Err },
+			}).SetBindings()
+			cc := &cobra.Command{Use: "validators"}
+			cc.Flags().AddFlagSet(cmd.FlagSet())
+			if err := cc.Flags().Parse([]string{}); err != nil {
+				t.Fatal(err)
+			}
+
+			report := errors.NewRegistry().Report(cc, cmd.ParseInput(cc, []string{}))
+			if report.Kind != errors.KindUsage || report.Code != statuscode.Usage {
+				t.Fatalf("not reported as a usage error: %+v", report)
+			}
+
+			if report.Option != c.Option || !reflect.DeepEqual(report.Problems, c.Problems) {
+				t.Fatalf("unexpected attribution: %+v", report)
+			}
+		})
+	}
+}
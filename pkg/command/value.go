@@ -29,6 +29,24 @@ const (
 	ValueTypeBoolean ValueType = "bool"
 	// ValueTypeBoolean is a value treated like an integer.
 	ValueTypeInt ValueType = "int"
+	// ValueTypeFloat is a value treated like a float64.
+	ValueTypeFloat ValueType = "float"
+	// ValueTypeDuration is a value treated like a time.Duration, i.e. 1h30m.
+	ValueTypeDuration ValueType = "duration"
+	// ValueTypeTime is a value treated like a time.Time, formatted as RFC3339 or a date, i.e. 2006-01-02.
+	ValueTypeTime ValueType = "time"
+	// ValueTypeURL is a value treated like a *url.URL.
+	ValueTypeURL ValueType = "url"
+	// ValueTypeIP is a value treated like a net.IP.
+	ValueTypeIP ValueType = "ip"
+	// ValueTypeCIDR is a value treated like a *net.IPNet, i.e. 10.0.0.0/8.
+	ValueTypeCIDR ValueType = "cidr"
+	// ValueTypeByteSize is a value treated like a ByteSize, i.e. 10MiB.
+	ValueTypeByteSize ValueType = "bytesize"
+	// ValueTypeMap is a value treated like a map[string]string, from comma-separated key=value pairs.
+	ValueTypeMap ValueType = "map"
+	// ValueTypeCount is an integer option incremented every time it's provided, i.e. -vvv.
+	ValueTypeCount ValueType = "count"
 )
 
 type SourceCommand struct {
@@ -50,7 +68,7 @@ type ValueSource struct {
 	Static *[]string `json:"static,omitempty" yaml:"static,omitempty" validate:"omitempty,excluded_with=Command Directories Files Func Script"`
 	// Command runs a subcommand and returns an option for every line of stdout.
 	Command *SourceCommand `json:"command,omitempty" yaml:"command,omitempty" validate:"omitempty,excluded_with=Directories Files Func Script Static"`
-	// Func runs a function
+	// Func runs a function, which may use cmd.Context() to know when the invocation is cancelled
 	Func CompletionFunc `json:"-" yaml:"-" validate:"omitempty,excluded_with=Command Directories Files Script Static"`
 	// Timeout is the maximum amount of time we will wait for a Script, Command, or Func before giving up on completions/validations.
 	Timeout int `json:"timeout,omitempty" yaml:"timeout,omitempty" validate:"omitempty,excluded_with=Directories Files Static"`
@@ -64,6 +82,18 @@ type ValueSource struct {
 	custom     string // The app-defined key's value
 }
 
+// clone returns a copy of vs without resolved values.
+func (vs *ValueSource) clone() *ValueSource {
+	if vs == nil {
+		return nil
+	}
+	clone := *vs
+	clone.command = nil
+	clone.computed = nil
+	clone.flag = cobra.ShellCompDirectiveDefault
+	return &clone
+}
+
 // Validates tells if a value needs to be validated.
 func (vs *ValueSource) Validates() bool {
 	if vs.Directories != nil || vs.Files != nil {
@@ -73,6 +103,14 @@ func (vs *ValueSource) Validates() bool {
 	return !vs.Suggestion
 }
 
+// context returns the context of the command this value source is bound to.
+func (vs *ValueSource) context() context.Context {
+	if vs.command == nil {
+		return context.Background()
+	}
+	return vs.command.Context()
+}
+
 // Resolve returns the values for autocomplete and validation.
 func (vs *ValueSource) Resolve(currentValue string) (values []string, flag cobra.ShellCompDirective, err error) {
 	if vs.computed != nil {
@@ -96,7 +134,7 @@ func (vs *ValueSource) Resolve(currentValue string) (values []string, flag cobra
 		flag = cobra.ShellCompDirectiveFilterDirs
 		values = []string{*vs.Directories}
 	case vs.Func != nil:
-		ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+		ctx, cancel := context.WithTimeout(vs.context(), timeout*time.Second)
 		defer cancel()
 
 		done := make(chan error, 1)
@@ -135,7 +173,7 @@ func (vs *ValueSource) Resolve(currentValue string) (values []string, flag cobra
 			return nil, cobra.ShellCompDirectiveError, fmt.Errorf("could not find a command named %s", vs.Command.Path)
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Second)
+		ctx, cancel := context.WithTimeout(vs.context(), timeout*time.Second)
 		defer cancel() // The cancel should be deferred so resources are cleaned up
 
 		sub.SetArgs(args)
@@ -161,7 +199,7 @@ func (vs *ValueSource) Resolve(currentValue string) (values []string, flag cobra
 
 		args := append([]string{"/bin/bash", "-c"}, cmd)
 
-		values, flag, err = exec.Exec(vs.command.FullName(), args, os.Environ(), timeout*time.Second, log)
+		values, flag, err = exec.ExecContext(vs.context(), vs.command.FullName(), args, os.Environ(), timeout*time.Second, vs.command.log())
 		if err != nil {
 			return nil, flag, err
 		}
@@ -235,30 +273,26 @@ func (vs *ValueSource) UnmarshalYAML(node *yaml.Node) error {
 	vs.computed = nil
 	intermediate := map[string]yaml.Node{}
 	if err := node.Decode(&intermediate); err != nil {
-		log.Errorf("could not decode valuesource: %s", err)
-		return err
+		return fmt.Errorf("could not decode valuesource: %w", err)
 	}
 
 	if t, ok := intermediate["timeout"]; ok {
 		if err := t.Decode(&vs.Timeout); err != nil {
-			log.Errorf("could not decode timeout: %s", err)
-			return err
+			return fmt.Errorf("could not decode timeout: %w", err)
 		}
 		delete(intermediate, "timeout")
 	}
 
 	if t, ok := intermediate["suggest-only"]; ok {
 		if err := t.Decode(&vs.Suggestion); err != nil {
-			log.Errorf("could not decode suggest-only: %s", err)
-			return err
+			return fmt.Errorf("could not decode suggest-only: %w", err)
 		}
 		delete(intermediate, "suggest-only")
 	}
 
 	if t, ok := intermediate["suggest-raw"]; ok {
 		if err := t.Decode(&vs.SuggestRaw); err != nil {
-			log.Errorf("could not decode suggest-raw: %s", err)
-			return err
+			return fmt.Errorf("could not decode suggest-raw: %w", err)
 		}
 		delete(intermediate, "suggest-raw")
 	}
@@ -266,8 +300,7 @@ func (vs *ValueSource) UnmarshalYAML(node *yaml.Node) error {
 	for key, node := range intermediate {
 		if cfn, ok := customCompleters[key]; ok {
 			if err := node.Decode(&vs.custom); err != nil {
-				log.Errorf("could not decode custom: %s", err)
-				return err
+				return fmt.Errorf("could not decode custom: %w", err)
 			}
 			vs.Func = cfn
 			break
@@ -293,8 +326,7 @@ func (vs *ValueSource) UnmarshalYAML(node *yaml.Node) error {
 		case "static":
 			static := []string{}
 			if err := node.Decode(&static); err != nil {
-				log.Errorf("could not decode static: %s", err)
-				return err
+				return fmt.Errorf("could not decode static: %w", err)
 			}
 			vs.Static = &static
 		case "command":
@@ -0,0 +1,238 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+
+/*
+Package config loads layered configuration files supplying values for command options.
+
+Files are looked up, from lowest to highest precedence, at:
+
+  - the system directory, i.e. /etc/my-app/config.yaml
+  - the user's XDG config directory, i.e. ~/.config/my-app/config.toml
+  - the closest project file found walking up from the working directory, i.e. .my-app.json
+  - the path given to the --config global option
+
+Values are nested by command path and keyed by option name, with options set closer to the
+root of the file applying to every command below it:
+
+	# applies to any command with a --dry-run option
+	dry-run: true
+	deploy:
+	  app:
+	    # applies only to `my-app deploy app --replicas`
+	    replicas: 3
+
+YAML (.yaml, .yml), TOML (.toml) and JSON (.json) files are supported. Values found in these files
+are used only when neither flags nor environment variables supply one, so files are read only by commands with
+options missing a value. Files that cannot be parsed, or values invalid for their option, make the program exit with
+statuscode.ConfigError, naming the file and key at fault.
+*/
+package config
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"git.rob.mx/nidito/chinampa/pkg/errors"
+	"git.rob.mx/nidito/chinampa/pkg/statuscode"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// SystemDir holds system-wide configuration directories.
+var SystemDir = "/etc"
+
+// Extensions lists the supported config file extensions, in the order they are looked up.
+var Extensions = []string{".yaml", ".yml", ".toml", ".json"}
+
+// Layer holds the values read from a single config file.
+type Layer struct {
+	// Path is the location of the file these values were read from.
+	Path   string
+	values map[string]any
+}
+
+// Layers holds config values ordered from lowest to highest precedence.
+type Layers []*Layer
+
+// Load reads the system, user, project and explicit config files for the program called name, skipping any
+// that do not exist. An error is returned if explicit is not empty but could not be read, or any file is invalid.
+func Load(name, explicit string) (Layers, error) {
+	layers := Layers{}
+	for _, path := range []string{SystemPath(name), UserPath(name), ProjectPath(name)} {
+		if path == "" {
+			continue
+		}
+
+		layer, err := ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, layer)
+	}
+
+	if explicit != "" {
+		if _, err := os.Stat(explicit); err != nil {
+			return nil, errors.BadArguments{Msg: fmt.Sprintf("could not read config file %s: %s", explicit, err)}
+		}
+
+		layer, err := ReadFile(explicit)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, layer)
+	}
+
+	return layers, nil
+}
+
+// SystemPath returns the path to the system config file for name, or an empty string if none exists.
+func SystemPath(name string) string {
+	return find(filepath.Join(SystemDir, name), "config")
+}
+
+// UserPath returns the path to the user's config file for name, or an empty string if none exists.
+func UserPath(name string) string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+
+	return find(filepath.Join(dir, name), "config")
+}
+
+// ProjectPath returns the path to the closest .name file found by walking up from the working directory,
+// or an empty string if none exists.
+func ProjectPath(name string) string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+
+	for {
+		if path := find(dir, "."+name); path != "" {
+			return path
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func find(dir, base string) string {
+	for _, ext := range Extensions {
+		path := filepath.Join(dir, base+ext)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// Invalid returns an error exiting with statuscode.ConfigError, for a problem found at key of the config file at
+// path, or anywhere in it when key is empty.
+func Invalid(path, key string, err error) error {
+	if key != "" {
+		err = fmt.Errorf("could not parse config file %s at key %s: %w", path, key, err)
+	} else {
+		err = fmt.Errorf("could not parse config file %s: %w", path, err)
+	}
+	return errors.ExitError{Code: statuscode.ConfigError, Err: err, Hint: fmt.Sprintf("Fix or remove %s", path)}
+}
+
+// ReadFile parses the config file at path, in a format determined by its extension. Errors exit with
+// statuscode.ConfigError, see Invalid.
+func ReadFile(path string) (*Layer, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.ExitError{Code: statuscode.ConfigError, Err: fmt.Errorf("could not read config file %s: %w", path, err)}
+	}
+
+	values := map[string]any{}
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(contents, &values)
+	case ".toml":
+		err = toml.Unmarshal(contents, &values)
+	case ".json":
+		err = json.Unmarshal(contents, &values)
+	default:
+		return nil, errors.ExitError{Code: statuscode.ConfigError, Err: fmt.Errorf("unknown format for config file %s, expected one of %s", path, Extensions)}
+	}
+
+	if err != nil {
+		key := ""
+		var parseErr toml.ParseError
+		if stderrors.As(err, &parseErr) {
+			key = parseErr.LastKey
+		}
+		return nil, Invalid(path, key, err)
+	}
+
+	return &Layer{Path: path, values: values}, nil
+}
+
+// Lookup returns the value for option of the command at path, and the Layer it was found in. Layers with
+// higher precedence are looked up first, and within each, values set for path win over those set for its parents.
+func (layers Layers) Lookup(path []string, option string) (value any, layer *Layer, found bool) {
+	for idx := len(layers) - 1; idx >= 0; idx-- {
+		if value, found := layers[idx].Lookup(path, option); found {
+			return value, layers[idx], true
+		}
+	}
+
+	return nil, nil, false
+}
+
+// Lookup returns the value for option of the command at path in this layer.
+func (layer *Layer) Lookup(path []string, option string) (value any, found bool) {
+	value, _, found = layer.lookup(path, option)
+	return value, found
+}
+
+// Key returns the dotted key holding the value for option of the command at path in this layer, i.e.
+// "deploy.replicas" when set for every command below deploy, or an empty string if not found.
+func (layer *Layer) Key(path []string, option string) string {
+	_, depth, found := layer.lookup(path, option)
+	if !found {
+		return ""
+	}
+	return strings.Join(append(append([]string{}, path[0:depth]...), option), ".")
+}
+
+func (layer *Layer) lookup(path []string, option string) (value any, depth int, found bool) {
+	for depth := len(path); depth >= 0; depth-- {
+		table, ok := layer.table(path[0:depth])
+		if !ok {
+			continue
+		}
+
+		if value, found := table[option]; found {
+			return value, depth, true
+		}
+	}
+
+	return nil, 0, false
+}
+
+func (layer *Layer) table(path []string) (map[string]any, bool) {
+	table := layer.values
+	for _, segment := range path {
+		child, ok := table[segment].(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		table = child
+	}
+	return table, true
+}
@@ -0,0 +1,183 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package config_test
+
+import (
+	stderrors "errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "git.rob.mx/nidito/chinampa/pkg/config"
+	"git.rob.mx/nidito/chinampa/pkg/errors"
+	"git.rob.mx/nidito/chinampa/pkg/statuscode"
+)
+
+func writeFile(t *testing.T, path, contents string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func setup(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+
+	system := SystemDir
+	SystemDir = filepath.Join(root, "etc")
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "home", ".config"))
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	project := filepath.Join(root, "project", "nested", "deeper")
+	if err := os.MkdirAll(project, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(project); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		SystemDir = system
+		os.Chdir(wd) // nolint: errcheck
+	})
+	return root
+}
+
+func TestLoad(t *testing.T) {
+	root := setup(t)
+	writeFile(t, filepath.Join(root, "etc", "test", "config.yaml"), `
+name: system
+count: 1
+deploy:
+  name: system-deploy
+  app:
+    tags: [a, b]
+`)
+	writeFile(t, filepath.Join(root, "home", ".config", "test", "config.toml"), `
+count = 2
+
+[deploy]
+count = 3
+`)
+	writeFile(t, filepath.Join(root, "project", ".test.json"), `{"deploy": {"app": {"name": "project-app"}}}`)
+	writeFile(t, filepath.Join(root, "explicit.yml"), "deploy:\n  app:\n    count: 4\n")
+
+	layers, err := Load("test", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(layers) != 3 {
+		t.Fatalf("expected 3 layers, got %d", len(layers))
+	}
+
+	cases := []struct {
+		Path     []string
+		Option   string
+		Expected any
+		Source   string
+	}{
+		{Path: []string{}, Option: "name", Expected: "system", Source: "config.yaml"},
+		{Path: []string{"deploy"}, Option: "name", Expected: "system-deploy", Source: "config.yaml"},
+		{Path: []string{"deploy", "app"}, Option: "name", Expected: "project-app", Source: ".test.json"},
+		{Path: []string{"deploy", "app"}, Option: "tags", Expected: []any{"a", "b"}, Source: "config.yaml"},
+		{Path: []string{"other"}, Option: "count", Expected: int64(2), Source: "config.toml"},
+		{Path: []string{"deploy", "app"}, Option: "count", Expected: int64(3), Source: "config.toml"},
+	}
+
+	for _, c := range cases {
+		t.Run(strings.Join(append(c.Path, c.Option), " "), func(t *testing.T) {
+			value, layer, found := layers.Lookup(c.Path, c.Option)
+			if !found {
+				t.Fatalf("value not found")
+			}
+			if !reflect.DeepEqual(value, c.Expected) {
+				t.Fatalf("unexpected value, wanted %#v, got %#v", c.Expected, value)
+			}
+			if filepath.Base(layer.Path) != c.Source {
+				t.Fatalf("unexpected source, wanted %s, got %s", c.Source, layer.Path)
+			}
+		})
+	}
+
+	if _, _, found := layers.Lookup([]string{"deploy"}, "missing"); found {
+		t.Fatalf("found unset value")
+	}
+
+	layers, err = Load("test", filepath.Join(root, "explicit.yml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if value, _, _ := layers.Lookup([]string{"deploy", "app"}, "count"); value != 4 {
+		t.Fatalf("explicit config did not take precedence, got %#v", value)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	root := setup(t)
+
+	if _, err := Load("test", filepath.Join(root, "missing.yaml")); err == nil || !strings.HasPrefix(err.Error(), "could not read config file") {
+		t.Fatalf("expected error reading missing explicit file, got %v", err)
+	}
+
+	writeFile(t, filepath.Join(root, "config.ini"), "name=ini")
+	if _, err := Load("test", filepath.Join(root, "config.ini")); err == nil || !strings.HasPrefix(err.Error(), "unknown format for config file") {
+		t.Fatalf("expected error for unknown format, got %v", err)
+	}
+
+	cases := []struct {
+		File     string
+		Contents string
+		Message  string
+	}{
+		{File: ".test.toml", Contents: "[deploy]\nname = ", Message: "could not parse config file %s at key deploy.name: "},
+		{File: ".test.yaml", Contents: "name: [", Message: "could not parse config file %s: yaml: line 1"},
+		{File: ".test.json", Contents: "{", Message: "could not parse config file %s: unexpected end of JSON input"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.File, func(t *testing.T) {
+			path := filepath.Join(root, "project", c.File)
+			writeFile(t, path, c.Contents)
+			t.Cleanup(func() { os.Remove(path) }) // nolint: errcheck
+
+			_, err := Load("test", "")
+			if err == nil || !strings.HasPrefix(err.Error(), fmt.Sprintf(c.Message, path)) {
+				t.Fatalf("expected error parsing bad file, got %v", err)
+			}
+
+			var exit errors.ExitError
+			if !stderrors.As(err, &exit) || exit.Code != statuscode.ConfigError {
+				t.Fatalf("expected config error status code, got %#v", err)
+			}
+		})
+	}
+}
+
+func TestLayerKey(t *testing.T) {
+	root := setup(t)
+	writeFile(t, filepath.Join(root, "project", ".test.yaml"), "replicas: 1\ndeploy:\n  app:\n    replicas: 2\n")
+	layers, err := Load("test", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for path, expected := range map[string]string{"deploy app": "deploy.app.replicas", "deploy": "replicas", "": "replicas"} {
+		if key := layers[0].Key(strings.Fields(path), "replicas"); key != expected {
+			t.Fatalf("unexpected key for %q, wanted %s, got %s", path, expected, key)
+		}
+	}
+
+	if key := layers[0].Key([]string{"deploy"}, "missing"); key != "" {
+		t.Fatalf("found key for unset value: %s", key)
+	}
+}
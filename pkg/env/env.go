@@ -15,6 +15,7 @@ Package env holds environment variable names that are meant to be overridden by
 		env.Verbose = "MY_APP_VERBOSE"
 		env.Silent = "MY_APP_SILENT"
 		env.ValidationDisabled = "MY_APP_SKIP_VALIDATION"
+		env.OptionPrefix = "MY_APP_"
 	}
 */
 package env
@@ -22,7 +23,7 @@ package env
 // HelpStyle identifies the theme to use for help formatting.
 var HelpStyle = "HELP_STYLE"
 
-// Verbose enables verbose printing of log entries.
+// Verbose enables verbose printing of log entries, set to a number from 1 to 3 to increase verbosity.
 var Verbose = "VERBOSE"
 
 // Silent disables all printing of log entries, except for errors.
@@ -39,3 +40,63 @@ var ValidationDisabled = "SKIP_VALIDATION"
 
 // Debug enables printing of debugging information.
 var Debug = "DEBUG"
+
+// NoInput disables prompting for missing values.
+var NoInput = "NO_INPUT"
+
+// AssumeYes runs destructive commands without asking for confirmation.
+var AssumeYes = "ASSUME_YES"
+
+// ErrorFormat selects the format errors are reported in, either text or json.
+var ErrorFormat = "ERROR_FORMAT"
+
+// OptionPrefix, when set, derives the environment variable supplying values for options without an explicit one,
+// i.e. for MY_APP_, the value of --dry-run is read from MY_APP_DRY_RUN.
+var OptionPrefix = ""
+
+// Names holds the environment variable names read by an application.
+type Names struct {
+	HelpStyle          string
+	Verbose            string
+	Silent             string
+	NoColor            string
+	ForceColor         string
+	ValidationDisabled string
+	Debug              string
+	ErrorFormat        string
+	NoInput            string
+	AssumeYes          string
+	OptionPrefix       string
+}
+
+// Current returns the environment variable names currently in use.
+func Current() Names {
+	return Names{
+		HelpStyle:          HelpStyle,
+		Verbose:            Verbose,
+		Silent:             Silent,
+		NoColor:            NoColor,
+		ForceColor:         ForceColor,
+		ValidationDisabled: ValidationDisabled,
+		Debug:              Debug,
+		ErrorFormat:        ErrorFormat,
+		NoInput:            NoInput,
+		AssumeYes:          AssumeYes,
+		OptionPrefix:       OptionPrefix,
+	}
+}
+
+// Use makes names the ones in use.
+func (names Names) Use() {
+	HelpStyle = names.HelpStyle
+	Verbose = names.Verbose
+	Silent = names.Silent
+	NoColor = names.NoColor
+	ForceColor = names.ForceColor
+	ValidationDisabled = names.ValidationDisabled
+	Debug = names.Debug
+	ErrorFormat = names.ErrorFormat
+	NoInput = names.NoInput
+	AssumeYes = names.AssumeYes
+	OptionPrefix = names.OptionPrefix
+}
@@ -2,15 +2,39 @@
 // SPDX-License-Identifier: Apache-2.0
 package errors
 
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
 // NotFound happens when a sub-command was not found.
 type NotFound struct {
 	Msg   string
 	Group []string
+	// Suggestions are the names of commands the user may have meant.
+	Suggestions []string
 }
 
 // BadArguments happens when the user provided incorrect arguments or options.
 type BadArguments struct {
 	Msg string
+	// Argument names the offending argument, if any.
+	Argument string
+	// Option names the offending option, if any.
+	Option string
+}
+
+// flagInMessage matches the first flag named in a message, i.e. bar in "unknown flag: --bar".
+var flagInMessage = regexp.MustCompile(`(?:^|[\s"'])--([^\s"'=]+)`)
+
+// FlagError returns a BadArguments for err, returned when parsing flags, naming the option it mentions, if any.
+func FlagError(err error) BadArguments {
+	bad := BadArguments{Msg: err.Error()}
+	if match := flagInMessage.FindStringSubmatch(bad.Msg); match != nil {
+		bad.Option = match[1]
+	}
+	return bad
 }
 
 func (err NotFound) Error() string {
@@ -20,3 +44,45 @@ func (err NotFound) Error() string {
 func (err BadArguments) Error() string {
 	return err.Msg
 }
+
+// Multiple holds every problem found with an invocation, i.e. all failed validations.
+type Multiple struct {
+	Errors []error
+}
+
+// Combine returns nil if no errors are given, the only one if given a single error, or a Multiple holding
+// every non-nil error in order, with those of nested Multiple flattened.
+func Combine(errs ...error) error {
+	all := []error{}
+	for _, err := range errs {
+		switch err := err.(type) {
+		case nil:
+			continue
+		case Multiple:
+			all = append(all, err.Errors...)
+		default:
+			all = append(all, err)
+		}
+	}
+
+	switch len(all) {
+	case 0:
+		return nil
+	case 1:
+		return all[0]
+	}
+	return Multiple{Errors: all}
+}
+
+func (err Multiple) Error() string {
+	lines := []string{fmt.Sprintf("Found %d problems:", len(err.Errors))}
+	for _, current := range err.Errors {
+		lines = append(lines, "  - "+strings.ReplaceAll(current.Error(), "\n", "\n    "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Unwrap allows the standard library's errors.Is and errors.As to inspect each error.
+func (err Multiple) Unwrap() []error {
+	return err.Errors
+}
@@ -0,0 +1,51 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package errors_test
+
+import (
+	stderrors "errors"
+	"fmt"
+	"testing"
+
+	. "git.rob.mx/nidito/chinampa/pkg/errors"
+)
+
+func TestCombine(t *testing.T) {
+	if err := Combine(nil, nil); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	single := BadArguments{Msg: "single"}
+	if err := Combine(nil, single); err != single {
+		t.Fatalf("expected single error, got %v", err)
+	}
+
+	err := Combine(
+		BadArguments{Msg: "first"},
+		nil,
+		Combine(BadArguments{Msg: "second"}, fmt.Errorf("third\nwith details")),
+	)
+
+	multi, ok := err.(Multiple)
+	if !ok {
+		t.Fatalf("expected Multiple, got %T", err)
+	}
+
+	if len(multi.Errors) != 3 {
+		t.Fatalf("nested errors were not flattened: %v", multi.Errors)
+	}
+
+	expected := `Found 3 problems:
+  - first
+  - second
+  - third
+    with details`
+	if err.Error() != expected {
+		t.Fatalf("unexpected message:\ngot   : %s\nwanted: %s", err, expected)
+	}
+
+	var bad BadArguments
+	if !stderrors.As(err, &bad) || bad.Msg != "first" {
+		t.Fatalf("could not find BadArguments in %v", err)
+	}
+}
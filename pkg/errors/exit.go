@@ -0,0 +1,39 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package errors
+
+import (
+	"fmt"
+	"os"
+)
+
+// ExitError makes a program exit with Code, reporting Err and Hint, if any, to the user.
+type ExitError struct {
+	// Code is the status code to exit with, see the statuscode package.
+	Code int
+	// Err is the problem reported to the user, none is when nil.
+	Err error
+	// Hint tells the user how to fix the problem, if not empty.
+	Hint string
+}
+
+func (err ExitError) Error() string {
+	if err.Err == nil {
+		return fmt.Sprintf("exit status %d", err.Code)
+	}
+	return err.Err.Error()
+}
+
+// Unwrap allows the standard library's errors.Is and errors.As to inspect Err.
+func (err ExitError) Unwrap() error {
+	return err.Err
+}
+
+// Signaled is the cause of contexts cancelled upon receiving Signal, see context.Cause.
+type Signaled struct {
+	Signal os.Signal
+}
+
+func (err Signaled) Error() string {
+	return fmt.Sprintf("received %s", err.Signal)
+}
@@ -3,6 +3,7 @@
 package errors
 
 import (
+	"encoding/json"
 	"os"
 	"strings"
 
@@ -10,50 +11,93 @@ import (
 	"github.com/spf13/cobra"
 
 	_c "git.rob.mx/nidito/chinampa/internal/constants"
+	"git.rob.mx/nidito/chinampa/pkg/logger"
+	"git.rob.mx/nidito/chinampa/pkg/runtime"
 	"git.rob.mx/nidito/chinampa/pkg/statuscode"
 )
 
-func showHelp(cmd *cobra.Command) {
+// showHelp renders help for cmd, returning code unless rendering fails.
+func showHelp(cmd *cobra.Command, code int) int {
 	if cmd.Name() != _c.HelpCommandName {
 		err := cmd.Help()
 		if err != nil {
-			os.Exit(statuscode.ProgrammerError)
+			return statuscode.ProgrammerError
 		}
 	}
+	return code
 }
 
-// HandleCobraExit is called when a command errors out or was not found.
+// logLines logs message, followed by hint, skipping empty ones.
+func logLines(log *logrus.Entry, message, hint string) {
+	lines := []string{}
+	for _, line := range []string{message, hint} {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	if len(lines) > 0 {
+		log.Error(strings.Join(lines, "\n"))
+	}
+}
+
+// HandleCobraExit is called when a command errors out or was not found, and exits with the status code for err.
 func HandleCobraExit(cmd *cobra.Command, err error) error {
+	os.Exit(Handle(cmd, err))
+	return err
+}
+
+// Handle renders help and logs err as needed, returning the status code to exit with after running cmd. Errors are
+// mapped using the Default registry.
+func Handle(cmd *cobra.Command, err error) int {
+	return Default.Handle(cmd, err)
+}
+
+// Handle renders help and logs err as needed, returning the status code to exit with after running cmd. Errors
+// are printed to stderr as JSON instead when the invocation's runtime.Runtime requests it, see Report.
+func (r *Registry) Handle(cmd *cobra.Command, err error) int {
 	if err == nil {
-		ok, err := cmd.PersistentFlags().GetBool(_c.HelpCommandName)
+		ok, err := cmd.Flags().GetBool(_c.HelpCommandName)
 		if cmd.Name() == _c.HelpCommandName || err == nil && ok {
-			os.Exit(statuscode.RenderHelp)
+			return statuscode.RenderHelp
 		}
+		return statuscode.Ok
+	}
 
-		os.Exit(statuscode.Ok)
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = cmd.Root().Context()
 	}
+	log := logger.FromContext(ctx)
+	rt := runtime.FromContext(ctx)
+	// errors may happen before the command runs, so read global flags as parsed for it, if they were
+	rt.Bind(cmd.Flags(), cmd.Root().PersistentFlags())
 
-	switch err.(type) {
-	case BadArguments:
-		showHelp(cmd)
-		logrus.Error(err)
-		os.Exit(statuscode.Usage)
-	case NotFound:
-		showHelp(cmd)
-		logrus.Error(err)
-		os.Exit(statuscode.NotFound)
-	default:
-		if strings.HasPrefix(err.Error(), "unknown command") {
-			showHelp(cmd)
-			os.Exit(statuscode.NotFound)
-		} else if strings.HasPrefix(err.Error(), "unknown flag") || strings.HasPrefix(err.Error(), "unknown shorthand flag") {
-			showHelp(cmd)
-			logrus.Error(err)
-			os.Exit(statuscode.Usage)
+	report := r.Report(cmd, err)
+	if rt.ErrorFormat() == "json" {
+		if err := json.NewEncoder(cmd.ErrOrStderr()).Encode(report); err != nil {
+			log.Errorf("Could not report error as json: %s", err)
 		}
+		return report.Code
 	}
 
-	logrus.Errorf("Unknown error: %s", err)
-	os.Exit(2)
-	return err
+	switch report.Kind {
+	case KindExit:
+		message := report.Message
+		if report.silent {
+			message = ""
+		}
+		logLines(log, message, report.Hint)
+		return report.Code
+	case KindUsage, KindNotFound:
+		code := showHelp(cmd, report.Code)
+		log.Error(err)
+		return code
+	case KindInterrupted:
+		log.Warn(report.Message)
+		return report.Code
+	}
+
+	log.Errorf("Unknown error: %s", err)
+	return report.Code
 }
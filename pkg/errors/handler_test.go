@@ -0,0 +1,74 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package errors_test
+
+import (
+	"bytes"
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	. "git.rob.mx/nidito/chinampa/pkg/errors"
+	"git.rob.mx/nidito/chinampa/pkg/statuscode"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var errNoToken = stderrors.New("token missing")
+
+func TestHandle(t *testing.T) {
+	registry := NewRegistry()
+	registry.Map(errNoToken, Mapping{Code: statuscode.ConfigError, Message: "You are not logged in", Hint: "Run login first"})
+
+	cases := []struct {
+		Name string
+		Err  error
+		Code int
+		Log  string
+	}{
+		{Name: "ok", Code: statuscode.Ok},
+		{Name: "bad arguments", Err: BadArguments{Msg: "bad"}, Code: statuscode.Usage, Log: "bad"},
+		{Name: "wrapped bad arguments", Err: fmt.Errorf("parsing: %w", BadArguments{Msg: "bad"}), Code: statuscode.Usage, Log: "parsing: bad"},
+		{Name: "wrapped not found", Err: fmt.Errorf("finding: %w", NotFound{Msg: "missing"}), Code: statuscode.NotFound, Log: "finding: missing"},
+		{Name: "multiple", Err: Combine(BadArguments{Msg: "first"}, BadArguments{Msg: "second"}), Code: statuscode.Usage, Log: "Found 2 problems"},
+		{Name: "exit error", Err: ExitError{Code: 3, Err: fmt.Errorf("failed"), Hint: "try again"}, Code: 3, Log: "try again"},
+		{Name: "wrapped exit error", Err: fmt.Errorf("running: %w", ExitError{Code: 4}), Code: 4},
+		{Name: "mapped", Err: fmt.Errorf("auth: %w", errNoToken), Code: statuscode.ConfigError, Log: "You are not logged in"},
+		{Name: "unknown", Err: fmt.Errorf("boom"), Code: 2, Log: "Unknown error: boom"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			var logs bytes.Buffer
+			out := logrus.StandardLogger().Out
+			logrus.SetOutput(&logs)
+			defer logrus.SetOutput(out)
+
+			cmd := &cobra.Command{Use: "test", Run: func(cmd *cobra.Command, args []string) {}}
+			cmd.SetOut(&bytes.Buffer{})
+			cmd.SetErr(&bytes.Buffer{})
+
+			if code := registry.Handle(cmd, c.Err); code != c.Code {
+				t.Fatalf("unexpected code %d, expected %d", code, c.Code)
+			}
+
+			if c.Log == "" && logs.Len() > 0 {
+				t.Fatalf("unexpected log: %s", logs.String())
+			} else if !strings.Contains(logs.String(), c.Log) {
+				t.Fatalf("log does not contain %q: %s", c.Log, logs.String())
+			}
+		})
+	}
+}
+
+func TestExitError(t *testing.T) {
+	err := ExitError{Code: 3, Err: errNoToken}
+	if !stderrors.Is(err, errNoToken) {
+		t.Fatalf("could not unwrap %v", err)
+	}
+
+	if (ExitError{Code: 3}).Error() != "exit status 3" {
+		t.Fatalf("unexpected message: %s", ExitError{Code: 3})
+	}
+}
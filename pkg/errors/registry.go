@@ -0,0 +1,62 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package errors
+
+import (
+	stderrors "errors"
+	"sync"
+)
+
+// Mapping describes how to report a sentinel error to the user.
+type Mapping struct {
+	// Code is the status code to exit with, see the statuscode package.
+	Code int
+	// Message replaces that of the error, if not empty.
+	Message string
+	// Hint tells the user how to fix the problem, if not empty.
+	Hint string
+}
+
+type mapped struct {
+	sentinel error
+	mapping  Mapping
+}
+
+// Registry maps sentinel errors to the status codes and messages reported by Handle.
+type Registry struct {
+	mu       sync.RWMutex
+	mappings []mapped
+}
+
+// Default is the registry used by Handle and HandleCobraExit.
+var Default = NewRegistry()
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Map reports errors matching sentinel, as per the standard library's errors.Is, with mapping. Sentinels mapped
+// earlier take precedence.
+func (r *Registry) Map(sentinel error, mapping Mapping) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mappings = append(r.mappings, mapped{sentinel: sentinel, mapping: mapping})
+}
+
+// Lookup returns the mapping for the first sentinel matched by err.
+func (r *Registry) Lookup(err error) (Mapping, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, m := range r.mappings {
+		if stderrors.Is(err, m.sentinel) {
+			return m.mapping, true
+		}
+	}
+	return Mapping{}, false
+}
+
+// Map reports errors matching sentinel with mapping, using the Default registry.
+func Map(sentinel error, mapping Mapping) {
+	Default.Map(sentinel, mapping)
+}
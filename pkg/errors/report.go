@@ -0,0 +1,154 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package errors
+
+import (
+	"context"
+	stderrors "errors"
+	"regexp"
+	"strings"
+	"syscall"
+
+	"git.rob.mx/nidito/chinampa/pkg/statuscode"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// KindExit is reported for ExitError and mapped sentinel errors.
+	KindExit = "exit"
+	// KindUsage is reported when the user provided incorrect arguments or options.
+	KindUsage = "usage"
+	// KindNotFound is reported when a sub-command was not found.
+	KindNotFound = "not-found"
+	// KindInterrupted is reported when a command gave up after its context was cancelled, i.e. by SIGINT.
+	KindInterrupted = "interrupted"
+	// KindUnknown is reported for every other error.
+	KindUnknown = "unknown"
+)
+
+var colorEscapes = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// Problem describes one of the errors combined in a Multiple.
+type Problem struct {
+	Message  string `json:"message"`
+	Argument string `json:"argument,omitempty"`
+	Option   string `json:"option,omitempty"`
+}
+
+// Report describes an error for programs wrapping ours, printed to stderr as JSON when requested, see Registry.Handle.
+type Report struct {
+	// Kind is one of KindExit, KindUsage, KindNotFound, KindInterrupted or KindUnknown.
+	Kind string `json:"kind"`
+	// Message describes the error, without color escape codes.
+	Message string `json:"message"`
+	// Code is the status code the program exits with.
+	Code int `json:"code"`
+	// Command is the path of the command that ran, i.e. "my-program something".
+	Command string `json:"command"`
+	// Argument names the offending argument, if any.
+	Argument string `json:"argument,omitempty"`
+	// Option names the offending option, if any.
+	Option string `json:"option,omitempty"`
+	// Suggestions are the names of commands the user may have meant.
+	Suggestions []string `json:"suggestions,omitempty"`
+	// Hint tells the user how to fix the problem, if any.
+	Hint string `json:"hint,omitempty"`
+	// Problems lists every error found, when many were.
+	Problems []Problem `json:"problems,omitempty"`
+	silent   bool
+}
+
+func problemFor(err error) Problem {
+	problem := Problem{Message: colorEscapes.ReplaceAllString(err.Error(), "")}
+	var bad BadArguments
+	if stderrors.As(err, &bad) {
+		problem.Argument = bad.Argument
+		problem.Option = bad.Option
+	}
+	return problem
+}
+
+// Report describes err, returned after running cmd. Errors matching a mapped sentinel are reported as mapped,
+// otherwise the first ExitError, BadArguments or NotFound wrapped by err decides its kind and status code. Errors
+// wrapping context.Canceled are reported as interrupts, exiting with 128 plus the signal received, if any.
+func (r *Registry) Report(cmd *cobra.Command, err error) Report {
+	problem := problemFor(err)
+	report := Report{
+		Kind:     KindUnknown,
+		Message:  problem.Message,
+		Code:     2,
+		Command:  cmd.CommandPath(),
+		Argument: problem.Argument,
+		Option:   problem.Option,
+	}
+
+	if mapping, ok := r.Lookup(err); ok {
+		report.Kind = KindExit
+		report.Code = mapping.Code
+		report.Hint = mapping.Hint
+		if mapping.Message != "" {
+			report.Message = mapping.Message
+		}
+		return report
+	}
+
+	var exit ExitError
+	if stderrors.As(err, &exit) {
+		report.Kind = KindExit
+		report.Code = exit.Code
+		report.Hint = exit.Hint
+		report.silent = exit.Err == nil
+		return report
+	}
+
+	if stderrors.Is(err, context.Canceled) {
+		report.Kind = KindInterrupted
+		report.Message = "Interrupted"
+		report.Code = statuscode.Interrupted
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = cmd.Root().Context()
+		}
+		var signaled Signaled
+		if ctx != nil && stderrors.As(context.Cause(ctx), &signaled) {
+			if sig, ok := signaled.Signal.(syscall.Signal); ok {
+				report.Code = statuscode.ForSignal(sig)
+			}
+		}
+		return report
+	}
+
+	var multiple Multiple
+	if stderrors.As(err, &multiple) {
+		for _, err := range multiple.Errors {
+			report.Problems = append(report.Problems, problemFor(err))
+		}
+	}
+
+	var bad BadArguments
+	if stderrors.As(err, &bad) || len(report.Problems) > 0 {
+		report.Kind = KindUsage
+		report.Code = statuscode.Usage
+		return report
+	}
+
+	var notFound NotFound
+	if stderrors.As(err, &notFound) {
+		report.Kind = KindNotFound
+		report.Code = statuscode.NotFound
+		report.Suggestions = notFound.Suggestions
+		return report
+	}
+
+	message := err.Error()
+	if strings.HasPrefix(message, "unknown command") {
+		report.Kind = KindNotFound
+		report.Code = statuscode.NotFound
+	} else if strings.HasPrefix(message, "unknown flag") || strings.HasPrefix(message, "unknown shorthand flag") {
+		report.Kind = KindUsage
+		report.Code = statuscode.Usage
+		report.Option = FlagError(err).Option
+	}
+
+	return report
+}
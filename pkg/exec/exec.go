@@ -31,7 +31,12 @@ func WithSubshell(ctx context.Context, env []string, executable string, args ...
 
 // Exec runs a subprocess and returns a list of lines from stdout.
 func Exec(name string, args []string, env []string, timeout time.Duration, log *logrus.Entry) ([]string, cobra.ShellCompDirective, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	return ExecContext(context.Background(), name, args, env, timeout, log)
+}
+
+// ExecContext runs a subprocess that is killed once parent is done, and returns a list of lines from stdout.
+func ExecContext(parent context.Context, name string, args []string, env []string, timeout time.Duration, log *logrus.Entry) ([]string, cobra.ShellCompDirective, error) {
+	ctx, cancel := context.WithTimeout(parent, timeout)
 	defer cancel() // The cancel should be deferred so resources are cleaned up
 
 	log.Tracef("executing a subshell for %s", args)
@@ -46,6 +51,11 @@ func Exec(name string, args []string, env []string, timeout time.Duration, log *
 		return []string{}, cobra.ShellCompDirectiveError, fmt.Errorf("timed out resolving %s %s", executable, args)
 	}
 
+	if ctx.Err() == context.Canceled {
+		log.Debugf("cancelled running %s %s", executable, args)
+		return []string{}, cobra.ShellCompDirectiveError, ctx.Err()
+	}
+
 	if err != nil {
 		log.Debugf("error running %s %s: %s", executable, args, err)
 		return []string{}, cobra.ShellCompDirectiveError, errors.BadArguments{Msg: fmt.Sprintf("could not validate argument for command %s, ran <%s %s> failed: %s", name, executable, strings.Join(args, " "), err)}
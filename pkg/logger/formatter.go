@@ -33,15 +33,28 @@ func init() {
 	dimmed.EnableColor()
 }
 
+// ttyFormatter formats entries for humans, colored and verbose as requested for the invocation with runtime, or the
+// program's own when nil.
 type ttyFormatter struct {
+	runtime *runtime.Runtime
+}
+
+// NewFormatter returns the formatter for log entries of the invocation with runtime rt.
+func NewFormatter(rt *runtime.Runtime) logrus.Formatter {
+	return &ttyFormatter{runtime: rt}
 }
 
 func (f *ttyFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	rt := f.runtime
+	if rt == nil {
+		rt = runtime.Process()
+	}
+
 	prefix := ""
-	colorEnabled := runtime.ColorEnabled()
+	colorEnabled := rt.ColorEnabled()
 	message := entry.Message
 	switch {
-	case runtime.VerboseEnabled():
+	case rt.VerboseEnabled():
 		date := strings.Replace(entry.Time.Local().Format(time.DateTime), " ", "T", 1)
 		component := ""
 		if c, ok := entry.Data[componentKey]; ok {
@@ -4,6 +4,7 @@ package logger
 
 import (
 	"context"
+	"io"
 
 	"git.rob.mx/nidito/chinampa/pkg/runtime"
 	"github.com/sirupsen/logrus"
@@ -37,13 +38,88 @@ const (
 	LevelTrace
 )
 
+// VerbosityLevel returns the Level for the times verbose output was requested, from LevelInfo for -v up to LevelTrace for -vvv.
+func VerbosityLevel(count int) Level {
+	switch {
+	case count >= 3:
+		return LevelTrace
+	case count == 2:
+		return LevelDebug
+	}
+	return LevelInfo
+}
+
 // Configure sets up the Main logger.
 func Configure(name string, level Level) {
 	Main = logrus.WithField(componentKey, name)
-	if runtime.SilenceEnabled() {
-		logrus.SetLevel(logrus.ErrorLevel)
-	} else {
-		logrus.SetLevel(logrus.AllLevels[level])
+	SetLevel(level)
+}
+
+// SetLevel sets the level for all loggers, raised to the requested verbosity and lowered to LevelError if silenced.
+func SetLevel(level Level) {
+	logrus.SetLevel(logrus.AllLevels[LevelFor(runtime.Process(), level)])
+}
+
+// LevelFor returns level raised to the verbosity requested for the invocation with runtime rt, or LevelError if
+// it's silenced.
+func LevelFor(rt *runtime.Runtime, level Level) Level {
+	if rt.SilenceEnabled() {
+		return LevelError
+	}
+
+	if verbosity := rt.Verbosity(); verbosity > 0 && VerbosityLevel(verbosity) > level {
+		return VerbosityLevel(verbosity)
+	}
+	return level
+}
+
+// New returns a logger for the invocation with runtime rt, writing entries to out at level, see LevelFor.
+func New(out io.Writer, rt *runtime.Runtime, level Level) *logrus.Logger {
+	log := logrus.New()
+	log.SetOutput(out)
+	log.SetFormatter(NewFormatter(rt))
+	log.SetLevel(logrus.AllLevels[LevelFor(rt, level)])
+	return log
+}
+
+type contextKey struct{}
+
+// invocation holds the logger of an invocation, and the level it was configured at before verbosity was applied.
+type invocation struct {
+	log   *logrus.Entry
+	level Level
+}
+
+// NewContext returns a copy of ctx carrying log, the logger of an invocation configured at level before applying the
+// requested verbosity, see FromContext and Refresh.
+func NewContext(ctx context.Context, log *logrus.Entry, level Level) context.Context {
+	return context.WithValue(ctx, contextKey{}, invocation{log: log, level: level})
+}
+
+// FromContext returns the logger of the invocation ctx belongs to, or Main if ctx carries none.
+func FromContext(ctx context.Context) *logrus.Entry {
+	if ctx != nil {
+		if current, ok := ctx.Value(contextKey{}).(invocation); ok {
+			return current.log
+		}
+	}
+	return Main
+}
+
+// SubFromContext returns the logger of the invocation ctx belongs to, see FromContext, for the component called name.
+func SubFromContext(ctx context.Context, name string) *logrus.Entry {
+	return FromContext(ctx).WithField(componentKey, name)
+}
+
+// Refresh sets the level of the logger of the invocation ctx belongs to, if any, once the verbosity requested for it
+// is known to rt, see LevelFor.
+func Refresh(ctx context.Context, rt *runtime.Runtime) {
+	if ctx == nil {
+		return
+	}
+
+	if current, ok := ctx.Value(contextKey{}).(invocation); ok {
+		current.log.Logger.SetLevel(logrus.AllLevels[LevelFor(rt, current.level)])
 	}
 }
 
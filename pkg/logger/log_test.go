@@ -189,3 +189,19 @@ func TestFormatter(t *testing.T) {
 		})
 	}
 }
+
+func TestVerbosityLevel(t *testing.T) {
+	expected := map[int]Level{
+		0: LevelInfo,
+		1: LevelInfo,
+		2: LevelDebug,
+		3: LevelTrace,
+		4: LevelTrace,
+	}
+
+	for count, level := range expected {
+		if res := VerbosityLevel(count); res != level {
+			t.Fatalf("verbosity %d got level %d, wanted %d", count, res, level)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+
+/*
+Package progress shows the progress of long running tasks.
+
+On terminals, bars and spinners are redrawn in place, erased while log entries are printed and drawn again right
+after. Elsewhere, or when --silent or --verbose are given, progress is logged every Interval instead.
+
+Bars are drawn to a Screen, one per invocation of a command, that takes over the output of the invocation's logger
+only while bars are drawn on a terminal, and gives it back once they're done.
+
+	bar := progress.New(cmd.Stderr(), "downloading", len(files))
+	defer bar.Done()
+	for _, file := range files {
+		download(file)
+		bar.Add(1)
+	}
+*/
+package progress
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Interval is how often progress is logged when not drawn on a terminal.
+var Interval = 5 * time.Second
+
+// TerminalFunc tells if progress may be drawn to w. It is replaced in tests.
+var TerminalFunc = isTerminal
+
+// refreshRate is how often bars are redrawn on terminals.
+const refreshRate = 100 * time.Millisecond
+
+// barWidth is the amount of characters filled by complete bars.
+const barWidth = 30
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// screens holds the Screen used by New for each writer, while it shows bars in progress.
+var screens = struct {
+	sync.Mutex
+	byWriter map[io.Writer]*Screen
+}{byWriter: map[io.Writer]*Screen{}}
+
+// Bar shows the progress of a task, as a spinner when its total is unknown.
+type Bar struct {
+	screen      *Screen
+	label       string
+	total       int
+	current     int
+	started     time.Time
+	logged      time.Time
+	interactive bool
+	done        bool
+}
+
+// New starts showing the progress of a task called label, drawing it to w if it's a terminal. Bars with a total
+// of zero or less are shown as spinners. Done must be called once the task finishes.
+//
+// Bars drawn to the same writer share a Screen logging through logger.Main, while programs running several
+// invocations at once give each its own, see NewScreen.
+func New(w io.Writer, label string, total int) *Bar {
+	screens.Lock()
+	screen, ok := screens.byWriter[w]
+	if !ok {
+		screen = NewScreen(w, nil, nil)
+		screen.shared = true
+		screens.byWriter[w] = screen
+	}
+	screens.Unlock()
+
+	return screen.New(label, total)
+}
+
+// Spinner starts showing a task called label, whose total is unknown. Done must be called once the task finishes.
+func Spinner(w io.Writer, label string) *Bar {
+	return New(w, label, 0)
+}
+
+// Finish calls Done on every bar started with New or Spinner still in progress.
+func Finish() {
+	screens.Lock()
+	started := screens.byWriter
+	screens.byWriter = map[io.Writer]*Screen{}
+	screens.Unlock()
+
+	for _, screen := range started {
+		screen.Finish()
+	}
+}
+
+// forget stops sharing s once its bars are done, unless others were started since, so writers are not held onto.
+func forget(s *Screen) {
+	screens.Lock()
+	defer screens.Unlock()
+	if screens.byWriter[s.out] != s {
+		return
+	}
+
+	s.mu.Lock()
+	idle := len(s.bars) == 0
+	s.mu.Unlock()
+	if idle {
+		delete(screens.byWriter, s.out)
+	}
+}
+
+// Add increments the progress of this bar by n.
+func (bar *Bar) Add(n int) {
+	bar.screen.mu.Lock()
+	defer bar.screen.mu.Unlock()
+	bar.current += n
+}
+
+// Set sets the progress of this bar to current.
+func (bar *Bar) Set(current int) {
+	bar.screen.mu.Lock()
+	defer bar.screen.mu.Unlock()
+	bar.current = current
+}
+
+// SetLabel changes the label shown for this bar.
+func (bar *Bar) SetLabel(label string) {
+	bar.screen.mu.Lock()
+	defer bar.screen.mu.Unlock()
+	bar.label = label
+}
+
+// Done stops showing progress, leaving a final line for this bar on terminals, or logging it elsewhere. Calling
+// Done more than once has no effect.
+func (bar *Bar) Done() {
+	s := bar.screen
+	s.mu.Lock()
+	if bar.done {
+		s.mu.Unlock()
+		return
+	}
+	bar.done = true
+	message := bar.summary()
+
+	if bar.interactive {
+		s.clear()
+		fmt.Fprintln(s.out, s.fit(bar.line(0)))
+	}
+	s.remove(bar)
+	s.redraw()
+	release := s.releasing()
+	stop := s.stopping()
+	s.mu.Unlock()
+
+	// giving the logger back and waiting for the loop without holding mu, since both may be writing log entries
+	release()
+	stop()
+	if !bar.interactive {
+		s.log.Info(message)
+	}
+	if s.shared {
+		forget(s)
+	}
+}
+
+func (bar *Bar) elapsed() time.Duration {
+	return time.Since(bar.started).Round(time.Second)
+}
+
+// progress describes how far along this bar is, i.e. "21/50 (42%)", or "21" for spinners.
+func (bar *Bar) progress() string {
+	if bar.total <= 0 {
+		if bar.current > 0 {
+			return fmt.Sprintf("%d", bar.current)
+		}
+		return ""
+	}
+	return fmt.Sprintf("%d/%d (%d%%)", bar.current, bar.total, bar.percent())
+}
+
+func (bar *Bar) percent() int {
+	if bar.total <= 0 {
+		return 0
+	}
+	percent := bar.current * 100 / bar.total
+	if percent > 100 {
+		return 100
+	}
+	return percent
+}
+
+// line renders this bar for terminals, showing frame for spinners.
+func (bar *Bar) line(frame int) string {
+	if bar.total <= 0 {
+		marker := spinnerFrames[frame%len(spinnerFrames)]
+		if bar.done {
+			marker = "✔"
+		}
+		parts := []string{marker, bar.label}
+		if progress := bar.progress(); progress != "" {
+			parts = append(parts, progress)
+		}
+		return strings.Join(append(parts, bar.elapsed().String()), " ")
+	}
+
+	filled := barWidth * bar.percent() / 100
+	return fmt.Sprintf("%s [%s%s] %s %s", bar.label, strings.Repeat("=", filled), strings.Repeat(" ", barWidth-filled), bar.progress(), bar.elapsed())
+}
+
+// status is logged periodically for bars not drawn on terminals.
+func (bar *Bar) status() string {
+	if progress := bar.progress(); progress != "" {
+		return fmt.Sprintf("%s: %s, %s elapsed", bar.label, progress, bar.elapsed())
+	}
+	return fmt.Sprintf("%s: %s elapsed", bar.label, bar.elapsed())
+}
+
+// summary is logged once bars not drawn on terminals are done.
+func (bar *Bar) summary() string {
+	if progress := bar.progress(); progress != "" {
+		return fmt.Sprintf("%s: %s, done in %s", bar.label, progress, bar.elapsed())
+	}
+	return fmt.Sprintf("%s: done in %s", bar.label, bar.elapsed())
+}
@@ -0,0 +1,189 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package progress_test
+
+import (
+	"bytes"
+	"io"
+	goruntime "runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"git.rob.mx/nidito/chinampa/chinampatest"
+	"git.rob.mx/nidito/chinampa/pkg/env"
+	"git.rob.mx/nidito/chinampa/pkg/logger"
+	. "git.rob.mx/nidito/chinampa/pkg/progress"
+	"git.rob.mx/nidito/chinampa/pkg/runtime"
+	"github.com/sirupsen/logrus"
+)
+
+func setup(t *testing.T, terminal bool, args ...string) *bytes.Buffer {
+	t.Helper()
+	chinampatest.Reset(t)
+	chinampatest.Args(t, args...)
+	out := &bytes.Buffer{}
+	logrus.SetOutput(out)
+	logger.SetLevel(logger.LevelInfo)
+	TerminalFunc = func(w io.Writer) bool { return terminal }
+	return out
+}
+
+func TestTerminal(t *testing.T) {
+	out := setup(t, true)
+
+	first := New(out, "copying", 4)
+	second := Spinner(out, "waiting")
+	first.Add(2)
+	logger.Info("halfway there")
+	first.Done()
+	second.Set(3)
+	second.Done()
+
+	if logrus.StandardLogger().Out != out {
+		t.Fatalf("logrus output was not restored")
+	}
+
+	lines := strings.Split(out.String(), "\n")
+	logged := -1
+	for idx, line := range lines {
+		if strings.HasSuffix(line, "halfway there") {
+			logged = idx
+		}
+	}
+
+	if logged < 0 || !strings.Contains(lines[logged], "\x1b[2A\r\x1b[J") {
+		t.Fatalf("bars were not erased before logging: %q", out.String())
+	}
+
+	if !strings.HasPrefix(lines[logged+1], "copying [===============               ] 2/4 (50%)") ||
+		!strings.Contains(lines[logged+2], "waiting") {
+		t.Fatalf("bars were not drawn again after logging: %q", lines[logged+1:])
+	}
+
+	last := lines[len(lines)-2]
+	if !strings.Contains(last, "✔ waiting 3 0s") {
+		t.Fatalf("unexpected final line: %q", last)
+	}
+}
+
+func TestLogged(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Terminal bool
+		Args     []string
+	}{
+		{Name: "not a terminal"},
+		{Name: "silent", Terminal: true, Args: []string{"--silent"}},
+		{Name: "verbose", Terminal: true, Args: []string{"--verbose"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			out := setup(t, c.Terminal, c.Args...)
+			interval := Interval
+			Interval = 10 * time.Millisecond
+			t.Cleanup(func() { Interval = interval })
+
+			bar := New(out, "copying", 4)
+			bar.Add(1)
+			time.Sleep(250 * time.Millisecond)
+			bar.Add(3)
+			bar.Done()
+			bar.Done()
+			Finish()
+
+			logs := out.String()
+			if strings.Contains(logs, "\x1b[J") {
+				t.Fatalf("progress was drawn: %q", logs)
+			}
+
+			if len(c.Args) > 0 && c.Args[0] == "--silent" {
+				if logs != "" {
+					t.Fatalf("progress was logged while silenced: %q", logs)
+				}
+				return
+			}
+
+			for _, expected := range []string{"copying…", "copying: 1/4 (25%), 0s elapsed", "copying: 4/4 (100%), done in 0s"} {
+				if !strings.Contains(logs, expected) {
+					t.Fatalf("%q was not logged: %q", expected, logs)
+				}
+			}
+
+			if strings.Count(logs, "done in") != 1 {
+				t.Fatalf("done was logged more than once: %q", logs)
+			}
+		})
+	}
+}
+
+func TestScreens(t *testing.T) {
+	setup(t, true)
+	rt := runtime.New("test", env.Current(), nil)
+	newScreen := func() (*Screen, *logrus.Logger, *bytes.Buffer) {
+		out := &bytes.Buffer{}
+		log := logger.New(out, rt, logger.LevelInfo)
+		return NewScreen(out, logrus.NewEntry(log), rt), log, out
+	}
+
+	first, firstLog, firstOut := newScreen()
+	second, secondLog, secondOut := newScreen()
+	copying := first.New("copying", 2)
+	waiting := second.New("waiting", 2)
+	if firstLog.Out == io.Writer(firstOut) || secondLog.Out == io.Writer(secondOut) {
+		t.Fatalf("loggers were not taken over while drawing bars")
+	}
+
+	first.Finish()
+	if firstLog.Out != io.Writer(firstOut) || secondLog.Out == io.Writer(secondOut) {
+		t.Fatalf("finishing a screen did not give its logger back, or gave another's")
+	}
+
+	waiting.Add(1)
+	waiting.Done()
+	copying.Add(1)
+	if !strings.Contains(secondOut.String(), "waiting [===============               ] 1/2 (50%)") {
+		t.Fatalf("finishing a screen ended bars of another: %q", secondOut.String())
+	}
+
+	if strings.Contains(firstOut.String(), "waiting") || strings.Contains(secondOut.String(), "copying") {
+		t.Fatalf("bars were drawn to the wrong screen: %q, %q", firstOut.String(), secondOut.String())
+	}
+
+	if secondLog.Out != io.Writer(secondOut) {
+		t.Fatalf("logger output was not restored once bars were done")
+	}
+	copying.Done()
+}
+
+func TestDoneReleasesWriters(t *testing.T) {
+	setup(t, false)
+	collected := make(chan struct{})
+	func() {
+		out := &bytes.Buffer{}
+		goruntime.SetFinalizer(out, func(*bytes.Buffer) { close(collected) })
+		bar := New(out, "copying", 1)
+		bar.Add(1)
+		bar.Done()
+	}()
+
+	for attempt := 0; attempt < 10; attempt++ {
+		goruntime.GC()
+		select {
+		case <-collected:
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	t.Fatalf("writer was held onto once its bars were done")
+}
+
+func TestNonInteractiveKeepsLoggerOutput(t *testing.T) {
+	out := setup(t, false)
+	bar := New(out, "copying", 2)
+	if logrus.StandardLogger().Out != out {
+		t.Fatalf("logger output was replaced for bars that are not drawn")
+	}
+	bar.Done()
+}
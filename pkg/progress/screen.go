@@ -0,0 +1,239 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"git.rob.mx/nidito/chinampa/pkg/logger"
+	"git.rob.mx/nidito/chinampa/pkg/runtime"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/term"
+)
+
+// Screen shows the bars of an invocation on its out, below log entries, while a loop redraws them and logs the
+// progress of the rest periodically.
+type Screen struct {
+	out     io.Writer
+	log     *logrus.Entry
+	runtime *runtime.Runtime
+	// mu guards the bars of this screen, and what's drawn for them.
+	mu    sync.Mutex
+	bars  []*Bar
+	lines int
+	frame int
+	// logOut is the output of log's logger, taken over while interactive bars are drawn.
+	logOut    io.Writer
+	takenOver bool
+	quit      chan struct{}
+	exited    chan struct{}
+	// shared screens are used by New for their writer, until their bars are done.
+	shared bool
+}
+
+// NewScreen returns a Screen drawing to out and logging through log, honoring the --silent and --verbose flags of
+// rt. A nil log or rt stand for logger.Main and runtime.Process().
+func NewScreen(out io.Writer, log *logrus.Entry, rt *runtime.Runtime) *Screen {
+	if log == nil {
+		log = logger.Main
+	}
+	return &Screen{out: out, log: log, runtime: rt}
+}
+
+// New starts showing the progress of a task called label on this screen, see progress.New.
+func (s *Screen) New(label string, total int) *Bar {
+	rt := s.runtime
+	if rt == nil {
+		rt = runtime.Process()
+	}
+
+	now := time.Now()
+	bar := &Bar{
+		screen:      s,
+		label:       label,
+		total:       total,
+		started:     now,
+		logged:      now,
+		interactive: TerminalFunc(s.out) && !rt.SilenceEnabled() && !rt.VerboseEnabled(),
+	}
+
+	s.mu.Lock()
+	s.bars = append(s.bars, bar)
+	if s.quit == nil {
+		s.quit = make(chan struct{})
+		s.exited = make(chan struct{})
+		go s.loop(s.quit, s.exited)
+	}
+
+	takeOver := bar.interactive && !s.takenOver
+	if takeOver {
+		s.takenOver = true
+		s.logOut = s.log.Logger.Out
+	}
+	s.mu.Unlock()
+
+	if takeOver {
+		// log entries are written while holding the logger's lock, then ours, so take it over without holding mu
+		s.log.Logger.SetOutput(&suspender{screen: s})
+	}
+
+	if bar.interactive {
+		s.mu.Lock()
+		s.redraw()
+		s.mu.Unlock()
+	} else {
+		s.log.Infof("%s…", label)
+	}
+	return bar
+}
+
+// Spinner starts showing a task called label on this screen, whose total is unknown, see progress.Spinner.
+func (s *Screen) Spinner(label string) *Bar {
+	return s.New(label, 0)
+}
+
+// Finish calls Done on every bar of this screen still in progress.
+func (s *Screen) Finish() {
+	s.mu.Lock()
+	bars := append([]*Bar{}, s.bars...)
+	s.mu.Unlock()
+
+	for _, bar := range bars {
+		bar.Done()
+	}
+}
+
+// releasing returns a func giving the output of the logger back once no interactive bars are left, unless it was
+// replaced since. It must be called holding mu, and the func returned without.
+func (s *Screen) releasing() func() {
+	for _, bar := range s.bars {
+		if bar.interactive {
+			return func() {}
+		}
+	}
+
+	if !s.takenOver {
+		return func() {}
+	}
+	s.takenOver = false
+
+	log, previous := s.log.Logger, s.logOut
+	return func() {
+		if suspender, ok := log.Out.(*suspender); ok && suspender.screen == s {
+			log.SetOutput(previous)
+		}
+	}
+}
+
+// stopping returns a func ending the loop updating bars once none are left. It must be called holding mu, and the
+// func returned without.
+func (s *Screen) stopping() func() {
+	if len(s.bars) > 0 || s.quit == nil {
+		return func() {}
+	}
+
+	quit, exited := s.quit, s.exited
+	s.quit, s.exited = nil, nil
+	return func() {
+		close(quit)
+		<-exited
+	}
+}
+
+func (s *Screen) loop(quit, exited chan struct{}) {
+	defer close(exited)
+	ticker := time.NewTicker(refreshRate)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-quit:
+			return
+		case <-ticker.C:
+		}
+
+		s.mu.Lock()
+		s.frame++
+		s.redraw()
+		messages := []string{}
+		for _, bar := range s.bars {
+			if !bar.interactive && time.Since(bar.logged) >= Interval {
+				bar.logged = time.Now()
+				messages = append(messages, bar.status())
+			}
+		}
+		s.mu.Unlock()
+
+		// logging without holding mu, since the suspender needs it to write entries
+		for _, message := range messages {
+			s.log.Info(message)
+		}
+	}
+}
+
+func (s *Screen) remove(bar *Bar) {
+	for idx, current := range s.bars {
+		if current == bar {
+			s.bars = append(s.bars[:idx], s.bars[idx+1:]...)
+			return
+		}
+	}
+}
+
+// clear erases the lines drawn for bars, leaving the cursor where the first one was drawn.
+func (s *Screen) clear() {
+	if s.lines > 0 {
+		fmt.Fprintf(s.out, "\x1b[%dA\r\x1b[J", s.lines)
+		s.lines = 0
+	}
+}
+
+// redraw draws interactive bars again, below the cursor.
+func (s *Screen) redraw() {
+	s.clear()
+	for _, bar := range s.bars {
+		if bar.interactive {
+			fmt.Fprintln(s.out, s.fit(bar.line(s.frame)))
+			s.lines++
+		}
+	}
+}
+
+// fit truncates line to the width of the terminal, so it's erased as a single line.
+func (s *Screen) fit(line string) string {
+	width := 80
+	if file, ok := s.out.(*os.File); ok {
+		if w, _, err := term.GetSize(int(file.Fd())); err == nil && w > 0 {
+			width = w
+		}
+	}
+
+	if utf8.RuneCountInString(line) < width {
+		return line
+	}
+	return string([]rune(line)[:width-1])
+}
+
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(file.Fd()))
+}
+
+// suspender erases bars before writing log entries, drawing them again afterwards.
+type suspender struct {
+	screen *Screen
+}
+
+func (w *suspender) Write(p []byte) (int, error) {
+	w.screen.mu.Lock()
+	defer w.screen.mu.Unlock()
+	w.screen.clear()
+	n, err := w.screen.logOut.Write(p)
+	w.screen.redraw()
+	return n, err
+}
@@ -0,0 +1,47 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+
+/*
+Package prompt asks users for values on a terminal.
+
+Commands prompt for missing required arguments and options through the Prompter returned by PrompterFunc, unless
+input is disabled with --no-input or stdin and stderr are not terminals.
+*/
+package prompt
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// ErrInterrupted is returned when the user cancels a prompt.
+var ErrInterrupted = errors.New("prompt cancelled by user")
+
+// Prompter asks users for values.
+type Prompter interface {
+	// Select asks for one of options, that users may filter by typing.
+	Select(label string, options []string) (string, error)
+	// Input asks for free text, asking again until validate returns no error.
+	Input(label string, validate func(value string) error) (string, error)
+	// Secret asks for free text without echoing it, asking again until validate returns no error.
+	Secret(label string, validate func(value string) error) (string, error)
+}
+
+// PrompterFunc returns a Prompter reading from in and writing to out, or nil if they're not terminals. It is
+// replaced in tests.
+var PrompterFunc = ForTerminal
+
+// ForTerminal returns a Terminal for in and out, or nil unless both are terminals.
+func ForTerminal(in io.Reader, out io.Writer) Prompter {
+	for _, stream := range []any{in, out} {
+		file, ok := stream.(*os.File)
+		if !ok || !term.IsTerminal(int(file.Fd())) {
+			return nil
+		}
+	}
+
+	return &Terminal{In: in, Out: out}
+}
@@ -0,0 +1,225 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package prompt
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// maxVisible is the most options shown at once by Select.
+const maxVisible = 10
+
+const (
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyBackspace = 8
+	keyTab       = 9
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+var (
+	keyUp   = []byte{keyEscape, '[', 'A'}
+	keyDown = []byte{keyEscape, '[', 'B'}
+)
+
+// Terminal prompts users on a terminal, reading from In and drawing prompts to Out. Keys are read in raw mode, and
+// secrets without echoing them, only if In is a terminal.
+type Terminal struct {
+	In     io.Reader
+	Out    io.Writer
+	reader *bufio.Reader
+}
+
+// fd returns the file descriptor of In, if it's a terminal.
+func (t *Terminal) fd() (int, bool) {
+	file, ok := t.In.(*os.File)
+	if !ok || !term.IsTerminal(int(file.Fd())) {
+		return 0, false
+	}
+	return int(file.Fd()), true
+}
+
+// Select asks for one of options: typing filters options, arrow keys move between them, enter selects one and
+// ctrl+c or escape cancel.
+func (t *Terminal) Select(label string, options []string) (string, error) {
+	if fd, ok := t.fd(); ok {
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return "", err
+		}
+		defer term.Restore(fd, state) // nolint:errcheck
+	}
+
+	return selectFrom(t.In, t.Out, label, options)
+}
+
+// Input asks for a line of text, until validate returns no error.
+func (t *Terminal) Input(label string, validate func(value string) error) (string, error) {
+	return ask(t.Out, label, validate, t.readLine)
+}
+
+// Secret asks for a line of text without echoing it, until validate returns no error.
+func (t *Terminal) Secret(label string, validate func(value string) error) (string, error) {
+	fd, ok := t.fd()
+	if !ok {
+		return ask(t.Out, label, validate, t.readLine)
+	}
+
+	return ask(t.Out, label, validate, func() (string, error) {
+		value, err := term.ReadPassword(fd)
+		fmt.Fprintln(t.Out)
+		return string(value), err
+	})
+}
+
+func (t *Terminal) readLine() (string, error) {
+	if t.reader == nil {
+		t.reader = bufio.NewReader(t.In)
+	}
+
+	line, err := t.reader.ReadString('\n')
+	if err != nil && (line == "" || !errors.Is(err, io.EOF)) {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// ask writes label and reads a value, repeating until validate returns no error.
+func ask(out io.Writer, label string, validate func(value string) error, read func() (string, error)) (string, error) {
+	for {
+		fmt.Fprintf(out, "%s: ", label)
+		value, err := read()
+		if errors.Is(err, io.EOF) {
+			return "", ErrInterrupted
+		} else if err != nil {
+			return "", err
+		}
+
+		if validate == nil {
+			return value, nil
+		}
+
+		if err := validate(value); err != nil {
+			fmt.Fprintf(out, "%s\n", err)
+			continue
+		}
+		return value, nil
+	}
+}
+
+// Filter returns the options containing query, ignoring case.
+func Filter(options []string, query string) []string {
+	query = strings.ToLower(query)
+	matches := []string{}
+	for _, option := range options {
+		if strings.Contains(strings.ToLower(option), query) {
+			matches = append(matches, option)
+		}
+	}
+	return matches
+}
+
+// selectFrom reads keys from in until one of options is selected, drawing them to out.
+func selectFrom(in io.Reader, out io.Writer, label string, options []string) (string, error) {
+	query := ""
+	cursor := 0
+	buf := make([]byte, 64)
+	for {
+		matches := Filter(options, query)
+		if cursor >= len(matches) {
+			cursor = len(matches) - 1
+		}
+		if cursor < 0 {
+			cursor = 0
+		}
+		draw(out, label, query, matches, cursor)
+
+		n, err := in.Read(buf)
+		if err != nil {
+			fmt.Fprint(out, "\r\x1b[J")
+			if errors.Is(err, io.EOF) {
+				return "", ErrInterrupted
+			}
+			return "", err
+		}
+		key := buf[:n]
+
+		switch {
+		case key[0] == keyCtrlC || key[0] == keyCtrlD || (n == 1 && key[0] == keyEscape):
+			fmt.Fprint(out, "\r\x1b[J")
+			return "", ErrInterrupted
+		case key[0] == '\r' || key[0] == '\n':
+			if len(matches) > 0 {
+				fmt.Fprintf(out, "\r\x1b[J%s: %s\r\n", label, matches[cursor])
+				return matches[cursor], nil
+			}
+		case bytes.Equal(key, keyUp) || key[0] == keyCtrlP:
+			cursor--
+			if cursor < 0 {
+				cursor = len(matches) - 1
+			}
+		case bytes.Equal(key, keyDown) || key[0] == keyCtrlN || key[0] == keyTab:
+			cursor++
+			if cursor >= len(matches) {
+				cursor = 0
+			}
+		case key[0] == keyDelete || key[0] == keyBackspace:
+			if query != "" {
+				_, size := utf8.DecodeLastRuneInString(query)
+				query = query[:len(query)-size]
+			}
+		case key[0] != keyEscape:
+			for _, char := range string(key) {
+				if unicode.IsPrint(char) {
+					query += string(char)
+				}
+			}
+		}
+	}
+}
+
+// draw writes the prompt line followed by visible matches, leaving the cursor at the end of the prompt line.
+func draw(out io.Writer, label, query string, matches []string, cursor int) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "\r\x1b[J%s: %s", label, query)
+
+	start := 0
+	if cursor >= maxVisible {
+		start = cursor - maxVisible + 1
+	}
+	end := start + maxVisible
+	if end > len(matches) {
+		end = len(matches)
+	}
+
+	lines := 0
+	for idx := start; idx < end; idx++ {
+		marker := "  "
+		if idx == cursor {
+			marker = "> "
+		}
+		fmt.Fprintf(&sb, "\r\n%s%s", marker, matches[idx])
+		lines++
+	}
+
+	if len(matches) == 0 {
+		sb.WriteString("\r\n  (no matches)")
+		lines++
+	}
+
+	fmt.Fprintf(&sb, "\x1b[%dA\r\x1b[%dC", lines, utf8.RuneCountInString(label)+2+utf8.RuneCountInString(query))
+	fmt.Fprint(out, sb.String())
+}
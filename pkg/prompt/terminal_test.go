@@ -0,0 +1,96 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package prompt_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	. "git.rob.mx/nidito/chinampa/pkg/prompt"
+)
+
+// keys returns a reader yielding a key per read, like terminals in raw mode do.
+type keys []string
+
+func (k *keys) Read(p []byte) (int, error) {
+	if len(*k) == 0 {
+		return 0, io.EOF
+	}
+
+	n := copy(p, (*k)[0])
+	*k = (*k)[1:]
+	return n, nil
+}
+
+func TestSelect(t *testing.T) {
+	options := []string{"alpha", "beta", "gamma", "delta"}
+	cases := []struct {
+		Name     string
+		Keys     keys
+		Expected string
+		Err      error
+	}{
+		{Name: "first", Keys: keys{"\r"}, Expected: "alpha"},
+		{Name: "down", Keys: keys{"\x1b[B", "\x1b[B", "\r"}, Expected: "gamma"},
+		{Name: "up wraps", Keys: keys{"\x1b[A", "\r"}, Expected: "delta"},
+		{Name: "filtered", Keys: keys{"E", "l", "\r"}, Expected: "delta"},
+		{Name: "backspace", Keys: keys{"x", "\x7f", "b", "\r"}, Expected: "beta"},
+		{Name: "no matches", Keys: keys{"x", "\r", "\x7f", "\r"}, Expected: "alpha"},
+		{Name: "cancelled", Keys: keys{"\x03"}, Err: ErrInterrupted},
+		{Name: "escaped", Keys: keys{"\x1b"}, Err: ErrInterrupted},
+		{Name: "eof", Keys: keys{"a"}, Err: ErrInterrupted},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			var out bytes.Buffer
+			term := &Terminal{In: &c.Keys, Out: &out}
+			res, err := term.Select("letter", options)
+			if !errors.Is(err, c.Err) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if res != c.Expected {
+				t.Fatalf("selected %q, expected %q", res, c.Expected)
+			}
+
+			if c.Err == nil && !strings.HasSuffix(out.String(), "letter: "+c.Expected+"\r\n") {
+				t.Fatalf("selection was not printed: %q", out.String())
+			}
+		})
+	}
+}
+
+func TestInput(t *testing.T) {
+	var out bytes.Buffer
+	term := &Terminal{In: strings.NewReader("\nshort\nlong enough\n"), Out: &out}
+	validate := func(value string) error {
+		if len(value) < 6 {
+			return fmt.Errorf("%q is too short", value)
+		}
+		return nil
+	}
+
+	res, err := term.Input("name", validate)
+	if err != nil || res != "long enough" {
+		t.Fatalf("unexpected input %q: %v", res, err)
+	}
+
+	if strings.Count(out.String(), "name: ") != 3 || !strings.Contains(out.String(), `"short" is too short`) {
+		t.Fatalf("unexpected prompts: %q", out.String())
+	}
+
+	if _, err := term.Secret("password", nil); !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("expected interruption once input ends, got %v", err)
+	}
+}
+
+func TestForTerminal(t *testing.T) {
+	if prompter := ForTerminal(strings.NewReader(""), &bytes.Buffer{}); prompter != nil {
+		t.Fatalf("got a prompter for streams that are not terminals")
+	}
+}
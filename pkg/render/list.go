@@ -0,0 +1,98 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+)
+
+// Pair is a key and its value, rendered by a List.
+type Pair struct {
+	Key   string
+	Value string
+}
+
+// List holds key/value pairs, i.e. the details of a single item.
+type List struct {
+	Pairs []Pair
+	// Wide prevents values from being truncated to fit the terminal.
+	Wide bool
+}
+
+// ListOf returns a list of the fields of a struct, in order, or the keys of a map, sorted. Anything else makes
+// for a single pair keyed value.
+func ListOf(data any) *List {
+	names, values, ok := fieldsOf(data)
+	if !ok {
+		names = []string{"value"}
+		values = []string{plainString(data)}
+	}
+
+	list := &List{Pairs: make([]Pair, len(names))}
+	for idx, name := range names {
+		list.Pairs[idx] = Pair{Key: name, Value: values[idx]}
+	}
+	return list
+}
+
+// Add appends a pair for key and value, formatted as plain output would.
+func (l *List) Add(key string, value any) *List {
+	l.Pairs = append(l.Pairs, Pair{Key: key, Value: plainString(value)})
+	return l
+}
+
+// Render writes a line for every pair to w, with bold keys followed by a colon and aligned values, and colors only
+// if o.Color and w is a terminal. Lines of values spanning many are indented, and truncated to fit the width of the
+// output, see Output.Width, unless Wide or o.Format is OutputWide. A nil o is NewOutput().
+func (l *List) Render(w io.Writer, o *Output) error {
+	if o == nil {
+		o = NewOutput()
+	}
+
+	keyWidth := 0
+	for _, pair := range l.Pairs {
+		if length := utf8.RuneCountInString(pair.Key) + 1; length > keyWidth {
+			keyWidth = length
+		}
+	}
+
+	valueWidth := 0
+	if width := o.widthFor(w); !l.Wide && o.Format != OutputWide && width > 0 {
+		valueWidth = width - keyWidth - 2
+		if valueWidth < minColumnWidth {
+			valueWidth = minColumnWidth
+		}
+	}
+
+	bold := color.New(color.Bold)
+	if o.colorsFor(w) {
+		bold.EnableColor()
+	} else {
+		bold.DisableColor()
+	}
+
+	indent := strings.Repeat(" ", keyWidth+2)
+	for _, pair := range l.Pairs {
+		key := pair.Key + ":"
+		key += strings.Repeat(" ", keyWidth-utf8.RuneCountInString(key))
+
+		lines := strings.Split(pair.Value, "\n")
+		for idx, line := range lines {
+			if valueWidth > 0 {
+				lines[idx] = truncate(line, valueWidth)
+			}
+		}
+
+		value := strings.Join(lines, "\n"+indent)
+		if _, err := fmt.Fprintln(w, strings.TrimRight(bold.Sprint(key)+"  "+value, " ")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,293 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+
+	"git.rob.mx/nidito/chinampa/pkg/runtime"
+	"github.com/alecthomas/chroma/v2/quick"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// OutputPlain renders data as lines of text.
+	OutputPlain = "plain"
+	// OutputJSON renders data as indented JSON.
+	OutputJSON = "json"
+	// OutputYAML renders data as YAML.
+	OutputYAML = "yaml"
+	// OutputTable renders data as a table, with a column for every field or key.
+	OutputTable = "table"
+	// OutputWide renders data as a table, without truncating columns to fit the terminal.
+	OutputWide = "wide"
+	// OutputTemplate renders data with a Go template.
+	OutputTemplate = "template"
+)
+
+// Output renders structured data in a format chosen by the user.
+type Output struct {
+	// Format is one of OutputPlain, OutputJSON, OutputYAML, OutputTable, OutputWide or OutputTemplate.
+	Format string
+	// Template renders data when Format is OutputTemplate, once for every item of slices.
+	Template string
+	// Color enables color escape codes, only ever written to terminals.
+	Color bool
+	// Width is the one tables and lists are truncated to fit, when greater than zero. Otherwise, they are truncated
+	// to the width of the terminal they're written to, and never when written elsewhere.
+	Width int
+}
+
+// NewOutput returns an Output in the format requested with --output and --format for the program's own invocation,
+// see OutputFor and runtime.Process.
+func NewOutput() *Output {
+	return OutputFor(runtime.Process())
+}
+
+// OutputFor returns an Output in the format requested with --output and --format for the invocation with runtime rt,
+// coloring output as rt.ColorEnabled and fitting it to the width of terminals it's written to.
+func OutputFor(rt *runtime.Runtime) *Output {
+	return &Output{
+		Format:   rt.OutputFormat(),
+		Template: rt.OutputTemplate(),
+		Color:    rt.ColorEnabled(),
+	}
+}
+
+// Data renders data to w in the format requested by the user, see NewOutput.
+func Data(w io.Writer, data any) error {
+	return NewOutput().Render(w, data)
+}
+
+// Render writes data to w in o.Format.
+func (o *Output) Render(w io.Writer, data any) error {
+	switch o.Format {
+	case OutputJSON:
+		res, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return err
+		}
+		return o.highlight(w, append(res, '\n'), "json")
+	case OutputYAML:
+		res, err := yaml.Marshal(data)
+		if err != nil {
+			return err
+		}
+		return o.highlight(w, res, "yaml")
+	case OutputTable, OutputWide:
+		return TableOf(data).Render(w, o)
+	case OutputTemplate:
+		return o.template(w, data)
+	case OutputPlain, "":
+		return plain(w, data)
+	}
+
+	return fmt.Errorf("unknown output format %s, expected one of plain, json, yaml, table, wide or template", o.Format)
+}
+
+// colorsFor tells if color escape codes should be written to w.
+func (o *Output) colorsFor(w io.Writer) bool {
+	if !o.Color {
+		return false
+	}
+
+	_, ok := terminal(w)
+	return ok
+}
+
+// widthFor returns the width output written to w is truncated to fit, or 0 if it should not be.
+func (o *Output) widthFor(w io.Writer) int {
+	if o.Width > 0 {
+		return o.Width
+	}
+	return terminalWidth(w)
+}
+
+func (o *Output) highlight(w io.Writer, source []byte, lexer string) error {
+	if !o.colorsFor(w) {
+		_, err := w.Write(source)
+		return err
+	}
+
+	return quick.Highlight(w, string(source), lexer, "terminal256", "monokai")
+}
+
+// OutputFuncs is a FuncMap for output templates, with TemplateFuncs and json.
+var OutputFuncs = template.FuncMap{
+	"json": func(data any) (string, error) {
+		res, err := json.Marshal(data)
+		return string(res), err
+	},
+}
+
+func (o *Output) template(w io.Writer, data any) error {
+	if o.Template == "" {
+		return fmt.Errorf("no template given, use --format to provide one")
+	}
+
+	tpl, err := template.New("output").Funcs(TemplateFuncs).Funcs(OutputFuncs).Parse(o.Template)
+	if err != nil {
+		return fmt.Errorf("could not parse output template: %w", err)
+	}
+
+	items := []any{data}
+	if value, ok := listValue(data); ok {
+		items = make([]any, value.Len())
+		for idx := range items {
+			items[idx] = value.Index(idx).Interface()
+		}
+	}
+
+	for _, item := range items {
+		var buf bytes.Buffer
+		if err := tpl.Execute(&buf, item); err != nil {
+			return fmt.Errorf("could not render output template: %w", err)
+		}
+
+		if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+			buf.WriteByte('\n')
+		}
+
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// plain writes Stringers and scalars on a line, fields and keys of structs and maps on lines of their own, and
+// every item of slices on a line, with their values separated by tabs. Stringers are never broken into fields.
+func plain(w io.Writer, data any) error {
+	if value, ok := listValue(data); ok {
+		for idx := 0; idx < value.Len(); idx++ {
+			item := value.Index(idx).Interface()
+			line := plainString(item)
+			if _, isStringer := item.(fmt.Stringer); !isStringer {
+				if _, fields, ok := fieldsOf(item); ok {
+					line = strings.Join(fields, "\t")
+				}
+			}
+
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if _, isStringer := data.(fmt.Stringer); isStringer {
+		_, err := fmt.Fprintln(w, plainString(data))
+		return err
+	}
+
+	if names, fields, ok := fieldsOf(data); ok {
+		for idx, name := range names {
+			if _, err := fmt.Fprintf(w, "%s: %s\n", name, fields[idx]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	_, err := fmt.Fprintln(w, plainString(data))
+	return err
+}
+
+func plainString(data any) string {
+	if data == nil {
+		return ""
+	}
+
+	value := reflect.ValueOf(data)
+	if value.Kind() == reflect.Pointer && value.IsNil() {
+		return ""
+	}
+
+	if str, ok := data.(fmt.Stringer); ok {
+		return str.String()
+	}
+
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return ""
+		}
+		value = value.Elem()
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+// listValue returns the value of data if it's a slice or array, other than bytes.
+func listValue(data any) (reflect.Value, bool) {
+	if _, ok := data.(fmt.Stringer); ok || data == nil {
+		return reflect.Value{}, false
+	}
+
+	value := reflect.ValueOf(data)
+	for value.Kind() == reflect.Pointer && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	kind := value.Kind()
+	if (kind == reflect.Slice || kind == reflect.Array) && value.Type().Elem().Kind() != reflect.Uint8 {
+		return value, true
+	}
+	return reflect.Value{}, false
+}
+
+// fieldsOf returns the names and values of the exported fields of a struct, in order, or the keys and values of a
+// map, sorted by key. Names come from json tags, if any.
+func fieldsOf(data any) (names []string, values []string, ok bool) {
+	if data == nil {
+		return nil, nil, false
+	}
+
+	value := reflect.ValueOf(data)
+	for value.Kind() == reflect.Pointer && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		kind := value.Type()
+		for idx := 0; idx < kind.NumField(); idx++ {
+			field := kind.Field(idx)
+			if !field.IsExported() {
+				continue
+			}
+
+			name := field.Name
+			if tag, _, _ := strings.Cut(field.Tag.Get("json"), ","); tag == "-" {
+				continue
+			} else if tag != "" {
+				name = tag
+			}
+
+			names = append(names, name)
+			values = append(values, plainString(value.Field(idx).Interface()))
+		}
+		return names, values, len(names) > 0
+	case reflect.Map:
+		keys := map[string]reflect.Value{}
+		for _, key := range value.MapKeys() {
+			name := fmt.Sprint(key.Interface())
+			keys[name] = key
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			values = append(values, plainString(value.MapIndex(keys[name]).Interface()))
+		}
+		return names, values, true
+	}
+
+	return nil, nil, false
+}
@@ -0,0 +1,147 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package render_test
+
+import (
+	"bytes"
+	"testing"
+
+	"git.rob.mx/nidito/chinampa/chinampatest"
+	. "git.rob.mx/nidito/chinampa/pkg/render"
+)
+
+type planet struct {
+	Name   string `json:"name" yaml:"name"`
+	Moons  int    `json:"moons" yaml:"moons"`
+	secret string
+}
+
+func (p planet) String() string {
+	return p.Name
+}
+
+type moon struct {
+	Name   string `json:"name"`
+	Planet string `json:"planet"`
+}
+
+func TestOutput(t *testing.T) {
+	planets := []planet{{Name: "mercury", Moons: 0}, {Name: "earth", Moons: 1, secret: "life"}}
+	moons := []moon{{Name: "moon", Planet: "earth"}, {Name: "phobos", Planet: "mars"}}
+	cases := []struct {
+		Name     string
+		Output   Output
+		Data     any
+		Expected string
+	}{
+		{
+			Name:   "json",
+			Output: Output{Format: OutputJSON, Color: true},
+			Data:   planets[1],
+			Expected: `{
+  "name": "earth",
+  "moons": 1
+}
+`,
+		},
+		{
+			Name:     "yaml",
+			Output:   Output{Format: OutputYAML},
+			Data:     planets,
+			Expected: "- name: mercury\n  moons: 0\n- name: earth\n  moons: 1\n",
+		},
+		{
+			Name:     "table",
+			Output:   Output{Format: OutputTable, Color: true},
+			Data:     planets,
+			Expected: "NAME     MOONS\nmercury  0\nearth    1\n",
+		},
+		{
+			Name:     "truncated table",
+			Output:   Output{Format: OutputTable, Width: 10},
+			Data:     moons,
+			Expected: "NAME   PLAN…\nmoon   earth\nphob…  mars\n",
+		},
+		{
+			Name:     "table of values",
+			Output:   Output{Format: OutputTable},
+			Data:     []string{"a", "b"},
+			Expected: "VALUE\na\nb\n",
+		},
+		{
+			Name:     "template",
+			Output:   Output{Format: OutputTemplate, Template: "{{ .Name }} has {{ .Moons }} moons"},
+			Data:     planets,
+			Expected: "mercury has 0 moons\nearth has 1 moons\n",
+		},
+		{
+			Name:     "template with json",
+			Output:   Output{Format: OutputTemplate, Template: "{{ json . }}"},
+			Data:     moons[0],
+			Expected: "{\"name\":\"moon\",\"planet\":\"earth\"}\n",
+		},
+		{
+			Name:     "plain stringers",
+			Output:   Output{Format: OutputPlain},
+			Data:     planets,
+			Expected: "mercury\nearth\n",
+		},
+		{
+			Name:     "plain list",
+			Output:   Output{Format: OutputPlain},
+			Data:     moons,
+			Expected: "moon\tearth\nphobos\tmars\n",
+		},
+		{
+			Name:     "plain struct",
+			Output:   Output{},
+			Data:     &moons[1],
+			Expected: "name: phobos\nplanet: mars\n",
+		},
+		{
+			Name:     "plain map",
+			Output:   Output{},
+			Data:     map[string]any{"b": 2, "a": nil},
+			Expected: "a: \nb: 2\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := c.Output.Render(&buf, c.Data); err != nil {
+				t.Fatalf("could not render: %s", err)
+			}
+
+			if buf.String() != c.Expected {
+				t.Fatalf("unexpected output:\ngot   : %q\nwanted: %q", buf.String(), c.Expected)
+			}
+		})
+	}
+}
+
+func TestOutputErrors(t *testing.T) {
+	for _, output := range []Output{
+		{Format: "xml"},
+		{Format: OutputTemplate},
+		{Format: OutputTemplate, Template: "{{ .Name "},
+		{Format: OutputTemplate, Template: "{{ .Missing }}"},
+	} {
+		if err := output.Render(&bytes.Buffer{}, moon{}); err == nil {
+			t.Fatalf("rendered %+v without error", output)
+		}
+	}
+}
+
+func TestNewOutput(t *testing.T) {
+	chinampatest.Reset(t)
+	chinampatest.Args(t, "--format", "{{ .Name }}")
+	if output := NewOutput(); output.Format != OutputTemplate || output.Template != "{{ .Name }}" {
+		t.Fatalf("unexpected output %+v", output)
+	}
+
+	chinampatest.Args(t, "--output=JSON")
+	if output := NewOutput(); output.Format != OutputJSON {
+		t.Fatalf("unexpected output %+v", output)
+	}
+}
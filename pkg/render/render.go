@@ -4,6 +4,7 @@ package render
 
 import (
 	"bytes"
+	"io"
 	"os"
 	"strings"
 	"text/template"
@@ -20,12 +21,41 @@ func addBackticks(str []byte) []byte {
 	return bytes.ReplaceAll(str, []byte("﹅"), []byte("`"))
 }
 
-// Markdown renders markdown-formatted content to the tty.
+// terminal returns the file descriptor of w, if it's a terminal.
+func terminal(w io.Writer) (int, bool) {
+	file, ok := w.(interface{ Fd() uintptr })
+	if !ok || !term.IsTerminal(int(file.Fd())) {
+		return 0, false
+	}
+	return int(file.Fd()), true
+}
+
+// terminalWidth returns the width of the terminal w writes to, or 0 if w is not a terminal or its width is unknown.
+func terminalWidth(w io.Writer) int {
+	fd, ok := terminal(w)
+	if !ok {
+		return 0
+	}
+
+	width, _, err := term.GetSize(fd)
+	if err != nil {
+		logrus.Debugf("Could not get terminal width")
+		return 0
+	}
+	return width
+}
+
+// Markdown renders markdown-formatted content to the tty, in the style set by env.HelpStyle, see MarkdownStyled.
 func Markdown(content []byte, withColor bool) ([]byte, error) {
+	return MarkdownStyled(content, os.Getenv(env.HelpStyle), withColor)
+}
+
+// MarkdownStyled renders markdown-formatted content to the tty in style, one of light, dark, markdown or auto,
+// chosen by glamour when empty.
+func MarkdownStyled(content []byte, style string, withColor bool) ([]byte, error) {
 	content = addBackticks(content)
 	var styleFunc glamour.TermRendererOption
 
-	style := os.Getenv(env.HelpStyle)
 	if style == "markdown" {
 		// markdown will render frontmatter along content and will not format for
 		// tty readability
@@ -42,8 +72,8 @@ func Markdown(content []byte, withColor bool) ([]byte, error) {
 		default:
 			// Glamour selects a style for the user.
 			styleFunc = glamour.WithStandardStyle("auto")
-			if style != "" {
-				logger.Warnf("Unknown %s=%s, assuming \"auto\"", env.HelpStyle, style)
+			if style != "" && style != "auto" {
+				logger.Warnf("Unknown help style %s, assuming \"auto\"", style)
 			}
 		}
 	} else {
@@ -52,9 +82,8 @@ func Markdown(content []byte, withColor bool) ([]byte, error) {
 		styleFunc = glamour.WithStandardStyle("notty")
 	}
 
-	width, _, err := term.GetSize(0)
-	if err != nil {
-		logrus.Debugf("Could not get terminal width")
+	width := terminalWidth(os.Stdout)
+	if width == 0 {
 		width = 80
 	}
 
@@ -88,10 +117,16 @@ var TemplateFuncs = template.FuncMap{
 // TemplateCommandHelp holds a template for rendering command help.
 var TemplateCommandHelp *template.Template
 
+// HelpTemplate returns TemplateCommandHelp, setting it to a new help template for executableName if unset.
 func HelpTemplate(executableName string) *template.Template {
 	if TemplateCommandHelp == nil {
-		TemplateCommandHelp = template.Must(template.New("help").Funcs(TemplateFuncs).Parse(strings.ReplaceAll(_c.HelpTemplate, "@chinampa@", executableName)))
+		TemplateCommandHelp = NewHelpTemplate(executableName)
 	}
 
 	return TemplateCommandHelp
 }
+
+// NewHelpTemplate returns the default template for rendering command help of the program called executableName.
+func NewHelpTemplate(executableName string) *template.Template {
+	return template.Must(template.New("help").Funcs(TemplateFuncs).Parse(strings.ReplaceAll(_c.HelpTemplate, "@chinampa@", executableName)))
+}
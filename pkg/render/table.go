@@ -0,0 +1,252 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package render
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+)
+
+// Table holds rows of cells under a header.
+type Table struct {
+	Headers []string
+	Rows    [][]string
+	// SortBy names the column rows are sorted by, descending when prefixed with a dash, i.e. -AGE.
+	SortBy string
+	// Wide prevents columns from being truncated to fit the terminal.
+	Wide bool
+	// NoHeaders skips the header, for scripts to parse.
+	NoHeaders bool
+}
+
+// NewTable returns an empty table with headers.
+func NewTable(headers ...string) *Table {
+	return &Table{Headers: headers, Rows: [][]string{}}
+}
+
+// Add appends a row with cells, formatted as plain output would.
+func (t *Table) Add(cells ...any) *Table {
+	row := make([]string, len(cells))
+	for idx, cell := range cells {
+		row[idx] = plainString(cell)
+	}
+	t.Rows = append(t.Rows, row)
+	return t
+}
+
+// TableOf returns a table for data: a row for every item of a slice, or a single one otherwise, with a column for
+// every field of structs or key of maps, or a single VALUE column for anything else.
+func TableOf(data any) *Table {
+	items := []any{data}
+	if value, ok := listValue(data); ok {
+		items = make([]any, value.Len())
+		for idx := range items {
+			items[idx] = value.Index(idx).Interface()
+		}
+	}
+
+	table := &Table{Headers: []string{}, Rows: [][]string{}}
+	columns := map[string]int{}
+	records := []map[string]string{}
+	for _, item := range items {
+		names, values, ok := fieldsOf(item)
+		if !ok {
+			names = []string{"value"}
+			values = []string{plainString(item)}
+		}
+
+		record := map[string]string{}
+		for idx, name := range names {
+			if _, known := columns[name]; !known {
+				columns[name] = len(table.Headers)
+				table.Headers = append(table.Headers, name)
+			}
+			record[name] = values[idx]
+		}
+		records = append(records, record)
+	}
+
+	for _, record := range records {
+		row := make([]string, len(table.Headers))
+		for name, value := range record {
+			row[columns[name]] = value
+		}
+		table.Rows = append(table.Rows, row)
+	}
+
+	for idx, header := range table.Headers {
+		table.Headers[idx] = strings.ToUpper(header)
+	}
+
+	return table
+}
+
+// minColumnWidth is the narrowest a column is truncated to when fitting a table to the terminal.
+const minColumnWidth = 5
+
+// Render writes the table to w, with columns aligned and sorted by SortBy, a bold header unless NoHeaders, and colors
+// only if o.Color and w is a terminal. Columns are truncated to fit the width of the output, see Output.Width, unless
+// Wide or o.Format is OutputWide. A nil o is NewOutput().
+func (t *Table) Render(w io.Writer, o *Output) error {
+	if o == nil {
+		o = NewOutput()
+	}
+
+	rows, err := t.sorted()
+	if err != nil {
+		return err
+	}
+
+	if !t.NoHeaders {
+		rows = append([][]string{t.Headers}, rows...)
+	}
+
+	widths := make([]int, len(t.Headers))
+	for _, row := range rows {
+		for idx, cell := range row {
+			if length := utf8.RuneCountInString(cell); idx < len(widths) && length > widths[idx] {
+				widths[idx] = length
+			}
+		}
+	}
+
+	if width := o.widthFor(w); !t.Wide && o.Format != OutputWide && width > 0 {
+		widths = fit(widths, width)
+	}
+
+	bold := color.New(color.Bold)
+	if o.colorsFor(w) {
+		bold.EnableColor()
+	} else {
+		bold.DisableColor()
+	}
+
+	for rowIdx, row := range rows {
+		line := formatRow(row, widths)
+		if rowIdx == 0 && !t.NoHeaders {
+			line = bold.Sprint(line)
+		}
+
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sorted returns the rows sorted by the column named by SortBy, numerically if every value is a number.
+func (t *Table) sorted() ([][]string, error) {
+	rows := append([][]string{}, t.Rows...)
+	if t.SortBy == "" {
+		return rows, nil
+	}
+
+	name, descending := strings.CutPrefix(t.SortBy, "-")
+	column := -1
+	for idx, header := range t.Headers {
+		if strings.EqualFold(header, name) {
+			column = idx
+			break
+		}
+	}
+
+	if column == -1 {
+		return nil, fmt.Errorf("cannot sort by unknown column %s, expected one of %s", name, strings.Join(t.Headers, ", "))
+	}
+
+	numbers := make([]float64, len(rows))
+	numeric := true
+	for idx, row := range rows {
+		number, err := strconv.ParseFloat(cell(row, column), 64)
+		numbers[idx] = number
+		numeric = numeric && err == nil
+	}
+
+	order := make([]int, len(rows))
+	for idx := range order {
+		order[idx] = idx
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if descending {
+			a, b = b, a
+		}
+
+		if numeric {
+			return numbers[a] < numbers[b]
+		}
+		return cell(rows[a], column) < cell(rows[b], column)
+	})
+
+	sorted := make([][]string, len(rows))
+	for idx, original := range order {
+		sorted[idx] = rows[original]
+	}
+	return sorted, nil
+}
+
+func cell(row []string, column int) string {
+	if column < len(row) {
+		return row[column]
+	}
+	return ""
+}
+
+// fit narrows the widest columns, down to minColumnWidth, until columns separated by two spaces fit width.
+func fit(widths []int, width int) []int {
+	fitted := append([]int{}, widths...)
+	total := 2 * (len(fitted) - 1)
+	for _, columnWidth := range fitted {
+		total += columnWidth
+	}
+
+	for total > width {
+		widest := 0
+		for idx, columnWidth := range fitted {
+			if columnWidth > fitted[widest] {
+				widest = idx
+			}
+		}
+
+		if fitted[widest] <= minColumnWidth {
+			break
+		}
+		fitted[widest]--
+		total--
+	}
+
+	return fitted
+}
+
+// formatRow truncates and pads cells to widths, separating them by two spaces.
+func formatRow(row []string, widths []int) string {
+	cells := make([]string, len(widths))
+	for idx := range widths {
+		cells[idx] = truncate(cell(row, idx), widths[idx])
+		if idx < len(widths)-1 {
+			cells[idx] += strings.Repeat(" ", widths[idx]-utf8.RuneCountInString(cells[idx]))
+		}
+	}
+	return strings.TrimRight(strings.Join(cells, "  "), " ")
+}
+
+// truncate shortens str to width runes, ending it with an ellipsis if needed.
+func truncate(str string, width int) string {
+	if utf8.RuneCountInString(str) <= width {
+		return str
+	}
+
+	runes := []rune(str)
+	if width < 1 {
+		return ""
+	}
+	return string(runes[0:width-1]) + "…"
+}
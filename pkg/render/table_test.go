@@ -0,0 +1,148 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package render_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	. "git.rob.mx/nidito/chinampa/pkg/render"
+)
+
+func TestTable(t *testing.T) {
+	newTable := func() *Table {
+		return NewTable("NAME", "SIZE", "DESCRIPTION").
+			Add("beta", 10, "the second letter of the greek alphabet").
+			Add("alpha", 9, "the first letter of the greek alphabet").
+			Add("gamma", 100, nil)
+	}
+
+	cases := []struct {
+		Name     string
+		Table    *Table
+		Output   Output
+		Expected string
+	}{
+		{
+			Name:   "unsorted",
+			Table:  newTable(),
+			Output: Output{Width: 80},
+			Expected: `NAME   SIZE  DESCRIPTION
+beta   10    the second letter of the greek alphabet
+alpha  9     the first letter of the greek alphabet
+gamma  100
+`,
+		},
+		{
+			Name:   "sorted",
+			Table:  &Table{Headers: newTable().Headers, Rows: newTable().Rows, SortBy: "name"},
+			Output: Output{Width: 80},
+			Expected: `NAME   SIZE  DESCRIPTION
+alpha  9     the first letter of the greek alphabet
+beta   10    the second letter of the greek alphabet
+gamma  100
+`,
+		},
+		{
+			Name:   "sorted numerically, descending",
+			Table:  &Table{Headers: newTable().Headers, Rows: newTable().Rows, SortBy: "-SIZE", NoHeaders: true},
+			Output: Output{Width: 80},
+			Expected: `gamma  100
+beta   10   the second letter of the greek alphabet
+alpha  9    the first letter of the greek alphabet
+`,
+		},
+		{
+			Name:   "truncated",
+			Table:  newTable(),
+			Output: Output{Width: 30},
+			Expected: `NAME   SIZE  DESCRIPTION
+beta   10    the second lette…
+alpha  9     the first letter…
+gamma  100
+`,
+		},
+		{
+			Name:   "wide",
+			Table:  newTable(),
+			Output: Output{Width: 30, Format: OutputWide},
+			Expected: `NAME   SIZE  DESCRIPTION
+beta   10    the second letter of the greek alphabet
+alpha  9     the first letter of the greek alphabet
+gamma  100
+`,
+		},
+		{
+			Name:     "no color outside terminals",
+			Table:    NewTable("NAME").Add(time.Duration(0)),
+			Output:   Output{Color: true},
+			Expected: "NAME\n0s\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := c.Table.Render(&buf, &c.Output); err != nil {
+				t.Fatalf("could not render: %s", err)
+			}
+
+			if buf.String() != c.Expected {
+				t.Fatalf("unexpected output:\ngot   :\n%s\nwanted:\n%s", buf.String(), c.Expected)
+			}
+		})
+	}
+
+	if err := (&Table{Headers: []string{"NAME"}, SortBy: "size"}).Render(&bytes.Buffer{}, &Output{}); err == nil {
+		t.Fatalf("sorted by an unknown column")
+	}
+}
+
+func TestList(t *testing.T) {
+	list := ListOf(moon{Name: "phobos", Planet: "mars"}).Add("description", "fear\nand panic, with deimos")
+
+	var buf bytes.Buffer
+	if err := list.Render(&buf, &Output{Width: 30, Color: true}); err != nil {
+		t.Fatalf("could not render: %s", err)
+	}
+
+	expected := `name:         phobos
+planet:       mars
+description:  fear
+              and panic, with…
+`
+	if buf.String() != expected {
+		t.Fatalf("unexpected output:\ngot   :\n%s\nwanted:\n%s", buf.String(), expected)
+	}
+}
+
+func TestPipedOutputIsNotTruncated(t *testing.T) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	description := strings.Repeat("and panic ", 30)
+	table := TableOf([]moon{{Name: "phobos", Planet: description}})
+	list := ListOf(moon{Name: "phobos", Planet: description})
+	if err := table.Render(writer, &Output{Format: OutputTable}); err != nil {
+		t.Fatalf("could not render table: %s", err)
+	}
+	if err := list.Render(writer, &Output{}); err != nil {
+		t.Fatalf("could not render list: %s", err)
+	}
+	writer.Close()
+
+	res, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(res), "…") || strings.Count(string(res), strings.TrimSpace(description)) != 2 {
+		t.Fatalf("piped output was truncated: %q", res)
+	}
+}
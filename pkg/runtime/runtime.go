@@ -3,15 +3,21 @@
 
 /*
 Package runtime presents environment related information useful during your program's runtime.
+
+Every invocation of a program has a Runtime, carried by the context of the command it runs, see FromContext. The
+package-level functions describe the program's own invocation, see Process.
 */
 package runtime
 
 import (
+	"context"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 
 	"git.rob.mx/nidito/chinampa/pkg/env"
+	"github.com/spf13/pflag"
 )
 
 // Executable is the name of our binary, and should be set
@@ -59,105 +65,501 @@ func isTrueIsh(val string) bool {
 	return false
 }
 
-var _flags map[string]bool
+// process holds the global flags found in the arguments of the program's own invocation.
+var process struct {
+	sync.Mutex
+	args  []string
+	given *flagValues
+}
+
+// SetArgs sets the arguments global flags are looked up in, os.Args by default when nil, and resets the cached parsed global flags.
+func SetArgs(args []string) {
+	process.Lock()
+	defer process.Unlock()
+	process.args = args
+	process.given = nil
+}
 
 // ResetParsedFlagsCache resets the cached parsed global flags.
 func ResetParsedFlagsCache() {
-	_flags = nil
-}
-
-func flagInArgs(name string) bool {
-	if _flags == nil {
-		_flags = map[string]bool{}
-		for _, arg := range os.Args {
-			switch arg {
-			case "--verbose":
-				_flags["verbose"] = true
-				delete(_flags, "silent")
-			case "--silent":
-				_flags["silent"] = true
-				delete(_flags, "verbose")
-			case "--color":
-				_flags["color"] = true
-				delete(_flags, "no-color")
-			case "--no-color":
-				_flags["no-color"] = true
-				delete(_flags, "color")
-			case "--skip-validation":
-				_flags["skip-validation"] = true
+	process.Lock()
+	defer process.Unlock()
+	process.given = nil
+}
+
+// Process returns the Runtime of the program's own invocation, named Executable, that looks up global flags in os.Args,
+// or the arguments passed to SetArgs, and reads the environment variables currently named by the env package.
+func Process() *Runtime {
+	process.Lock()
+	defer process.Unlock()
+	if process.given == nil {
+		args := process.args
+		if args == nil {
+			args = os.Args
+		}
+		process.given = scan(args)
+	}
+
+	return &Runtime{Executable: Executable, Env: env.Current(), given: process.given}
+}
+
+// Runtime holds the settings of a single invocation of a program, read from its global flags and environment.
+//
+// Global flags are looked up in the arguments of the invocation until Bind is called with the flags parsed for the
+// command it runs.
+type Runtime struct {
+	// Executable is the name of the program.
+	Executable string
+	// Env names the environment variables read.
+	Env   env.Names
+	mu    sync.RWMutex
+	given *flagValues
+}
+
+// New returns the Runtime of an invocation of the program called executable with args, excluding the program name,
+// reading the environment variables named by names.
+func New(executable string, names env.Names, args []string) *Runtime {
+	return &Runtime{Executable: executable, Env: names, given: scan(args)}
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying rt, see FromContext.
+func NewContext(ctx context.Context, rt *Runtime) context.Context {
+	return context.WithValue(ctx, contextKey{}, rt)
+}
+
+// FromContext returns the Runtime of the invocation ctx belongs to, or Process() if ctx carries none.
+func FromContext(ctx context.Context) *Runtime {
+	if ctx != nil {
+		if rt, ok := ctx.Value(contextKey{}).(*Runtime); ok {
+			return rt
+		}
+	}
+	return Process()
+}
+
+// Bind makes rt read global flags from flags, the parsed flags of the command it runs, instead of looking them up in
+// its arguments. Only flags inherited from globals, the persistent flags of the root command, are read, leaving
+// those shadowed by options of the command alone. Unparsed flags are ignored.
+func (rt *Runtime) Bind(flags, globals *pflag.FlagSet) {
+	if flags == nil || globals == nil || !flags.Parsed() {
+		return
+	}
+
+	given := &flagValues{flags: map[string]bool{}, values: map[string]string{}}
+	globals.VisitAll(func(global *pflag.Flag) {
+		if flag := flags.Lookup(global.Name); flag != global || !flag.Changed {
+			return
+		}
+
+		value := global.Value.String()
+		switch name := global.Name; {
+		case name == "verbose":
+			given.flags[name] = true
+			given.verbosity, _ = strconv.Atoi(value)
+		case isValueFlag(name):
+			given.values[name] = value
+		default:
+			if on, err := strconv.ParseBool(value); err == nil && on {
+				given.flags[name] = true
 			}
 		}
+	})
+
+	// unlike arguments, parsed flags don't tell which was given last
+	if given.flags["silent"] {
+		given.verbosity = 0
+		delete(given.flags, "verbose")
+	}
+	if given.flags["color"] {
+		delete(given.flags, "no-color")
 	}
 
-	_, ok := _flags[name]
-	return ok
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.given = given
 }
 
-// DebugEnabled tells if debugging was requested.
-func DebugEnabled() bool {
-	return isTrueIsh(os.Getenv(env.Debug))
+// flagValues holds the global flags found in the arguments of an invocation.
+type flagValues struct {
+	flags     map[string]bool
+	values    map[string]string
+	verbosity int
+}
+
+// verbosityInArg returns how many times verbose output is requested by arg, i.e. 3 for -vvv.
+func verbosityInArg(arg string) (count int, isVerbose bool) {
+	switch {
+	case arg == "--verbose", arg == "--verbose=true":
+		return 1, true
+	case strings.HasPrefix(arg, "--verbose="):
+		value := strings.TrimPrefix(arg, "--verbose=")
+		if value == "false" {
+			return 0, true
+		}
+		count, err := strconv.Atoi(value)
+		return count, err == nil
+	case len(arg) > 1 && arg[0] == '-' && strings.Trim(arg[1:], "v") == "":
+		return len(arg) - 1, true
+	}
+	return 0, false
+}
+
+// valueFlags are the global flags taking a value.
+var valueFlags = []string{"error-format", "output", "format"}
+
+func isValueFlag(name string) bool {
+	for _, flag := range valueFlags {
+		if flag == name {
+			return true
+		}
+	}
+	return false
+}
+
+// valueInArg returns the name and value of the value flag at args[idx], given as --name=value or --name value.
+func valueInArg(args []string, idx int) (name, value string, ok bool) {
+	for _, name := range valueFlags {
+		if value, ok := strings.CutPrefix(args[idx], "--"+name+"="); ok {
+			return name, value, true
+		} else if args[idx] == "--"+name && idx+1 < len(args) {
+			return name, args[idx+1], true
+		}
+	}
+	return "", "", false
+}
+
+// scan returns the global flags found in args, up to a -- separating them from positional arguments.
+func scan(args []string) *flagValues {
+	given := &flagValues{flags: map[string]bool{}, values: map[string]string{}}
+	for idx := 0; idx < len(args); idx++ {
+		arg := args[idx]
+		if arg == "--" {
+			break
+		}
+
+		if name, value, ok := valueInArg(args, idx); ok {
+			given.values[name] = value
+			if arg == "--"+name {
+				// the value is the next argument, not a flag
+				idx++
+			}
+			continue
+		}
+
+		if count, ok := verbosityInArg(arg); ok {
+			if strings.HasPrefix(arg, "--verbose=") && arg != "--verbose=true" {
+				// --verbose=n sets, rather than increments, verbosity
+				given.verbosity = 0
+			}
+			given.verbosity += count
+			given.flags["verbose"] = true
+			delete(given.flags, "silent")
+			continue
+		}
+
+		name, on, ok := boolInArg(arg)
+		if !ok {
+			continue
+		}
+		if !on {
+			// i.e. --yes=false
+			delete(given.flags, name)
+			continue
+		}
+
+		given.flags[name] = true
+		switch name {
+		case "silent":
+			given.verbosity = 0
+			delete(given.flags, "verbose")
+		case "color":
+			delete(given.flags, "no-color")
+		case "no-color":
+			delete(given.flags, "color")
+		}
+	}
+
+	return given
+}
+
+// boolFlags are the global flags that are either on or off.
+var boolFlags = []string{"silent", "color", "no-color", "skip-validation", "no-input", "yes"}
+
+// boolInArg returns the name and value of the boolean flag given by arg, as --name or --name=value.
+func boolInArg(arg string) (name string, on bool, ok bool) {
+	flag, isFlag := strings.CutPrefix(arg, "--")
+	if !isFlag {
+		return "", false, false
+	}
+
+	name, value, hasValue := strings.Cut(flag, "=")
+	for _, known := range boolFlags {
+		if known != name {
+			continue
+		}
+
+		if !hasValue {
+			return name, true, true
+		}
+		on, err := strconv.ParseBool(value)
+		return name, on, err == nil
+	}
+	return "", false, false
+}
+
+func (rt *Runtime) flagValues() *flagValues {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+	return rt.given
+}
+
+func (rt *Runtime) flag(name string) bool {
+	return rt.flagValues().flags[name]
+}
+
+// value returns the value of the global flag called name, if given.
+func (rt *Runtime) value(name string) (string, bool) {
+	value, ok := rt.flagValues().values[name]
+	return value, ok
 }
 
 // DebugEnabled tells if debugging was requested.
-func ValidationEnabled() bool {
-	return !flagInArgs("skip-validation") && isFalseIsh(os.Getenv(env.ValidationDisabled))
+func (rt *Runtime) DebugEnabled() bool {
+	return isTrueIsh(os.Getenv(rt.Env.Debug))
+}
+
+// ValidationEnabled tells if arguments and options should be validated.
+func (rt *Runtime) ValidationEnabled() bool {
+	return !rt.flag("skip-validation") && isFalseIsh(os.Getenv(rt.Env.ValidationDisabled))
+}
+
+// InputEnabled tells if prompting users for missing values is allowed.
+func (rt *Runtime) InputEnabled() bool {
+	return !rt.flag("no-input") && isFalseIsh(os.Getenv(rt.Env.NoInput))
+}
+
+// AssumeYes tells if destructive commands should run without asking for confirmation.
+func (rt *Runtime) AssumeYes() bool {
+	return rt.flag("yes") || isTrueIsh(os.Getenv(rt.Env.AssumeYes))
 }
 
 // VerboseEnabled tells if verbose output was requested.
-func VerboseEnabled() bool {
-	if flagInArgs("silent") {
-		return false
+func (rt *Runtime) VerboseEnabled() bool {
+	return rt.Verbosity() > 0
+}
+
+// Verbosity returns how many times verbose output was requested, either by repeating
+// flags like `-vvv` or `--verbose --verbose`, or by setting a number or true-ish value in the environment.
+func (rt *Runtime) Verbosity() int {
+	if rt.flag("silent") {
+		return 0
 	}
-	return isTrueIsh(os.Getenv(env.Verbose)) || flagInArgs("verbose")
+
+	if rt.flag("verbose") {
+		return rt.flagValues().verbosity
+	}
+
+	value := os.Getenv(rt.Env.Verbose)
+	if count, err := strconv.Atoi(value); err == nil && count > 0 {
+		return count
+	}
+
+	if isTrueIsh(value) {
+		return 1
+	}
+	return 0
 }
 
 // SilenceEnabled tells if silencing of output was requested.
-func SilenceEnabled() bool {
-	if flagInArgs("verbose") {
+func (rt *Runtime) SilenceEnabled() bool {
+	if rt.flag("verbose") {
 		return false
 	}
-	if flagInArgs("silent") {
+	if rt.flag("silent") {
 		return true
 	}
 
-	return isTrueIsh(os.Getenv(env.Silent)) || flagInArgs("silent")
+	return isTrueIsh(os.Getenv(rt.Env.Silent))
 }
 
 // ColorEnabled tells if colorizing output was requested.
-func ColorEnabled() bool {
-	if flagInArgs("color") {
+func (rt *Runtime) ColorEnabled() bool {
+	if rt.flag("color") {
 		return true
 	}
 
 	// we're talking to ttys, we want color unless NO_COLOR/--no-color
-	return !(isTrueIsh(os.Getenv(env.NoColor)) || flagInArgs("no-color"))
+	return !(isTrueIsh(os.Getenv(rt.Env.NoColor)) || rt.flag("no-color"))
+}
+
+// ErrorFormat returns the format errors should be reported in, either text or json.
+func (rt *Runtime) ErrorFormat() string {
+	value, ok := rt.value("error-format")
+	if !ok {
+		value = os.Getenv(rt.Env.ErrorFormat)
+	}
+
+	if strings.ToLower(value) == "json" {
+		return "json"
+	}
+	return "text"
+}
+
+// OutputFormat returns the format requested for rendering data, one of plain, json, yaml, table, wide or template. It
+// defaults to template when an OutputTemplate is given, or plain otherwise.
+func (rt *Runtime) OutputFormat() string {
+	if value, ok := rt.value("output"); ok && value != "" {
+		return strings.ToLower(value)
+	}
+
+	if rt.OutputTemplate() != "" {
+		return "template"
+	}
+	return "plain"
+}
+
+// OutputTemplate returns the Go template requested for rendering data, if any, i.e. {{ .Name }}.
+func (rt *Runtime) OutputTemplate() string {
+	value, _ := rt.value("format")
+	return value
 }
 
 // HelpStyle returns the style to use when rendering help.
-func HelpStyle() string {
-	return strings.ToLower(os.Getenv(env.HelpStyle))
+func (rt *Runtime) HelpStyle() string {
+	return strings.ToLower(os.Getenv(rt.Env.HelpStyle))
+}
+
+// FromEnv returns the value of the global flag called name for this invocation, and the environment variable it was
+// read from, when the flag was not given, but set through the environment instead.
+func (rt *Runtime) FromEnv(name string) (value, variable string, ok bool) {
+	on := strconv.FormatBool(true)
+	switch name {
+	case "verbose":
+		if !rt.flag("verbose") && !rt.flag("silent") && rt.Verbosity() > 0 {
+			return strconv.Itoa(rt.Verbosity()), rt.Env.Verbose, true
+		}
+	case "silent":
+		if !rt.flag("verbose") && !rt.flag("silent") && rt.SilenceEnabled() {
+			return on, rt.Env.Silent, true
+		}
+	case "color", "no-color":
+		if !rt.flag("color") && !rt.flag("no-color") && isTrueIsh(os.Getenv(rt.Env.NoColor)) {
+			return strconv.FormatBool(name == "no-color"), rt.Env.NoColor, true
+		}
+	case "error-format":
+		if _, given := rt.value(name); !given && os.Getenv(rt.Env.ErrorFormat) != "" {
+			return rt.ErrorFormat(), rt.Env.ErrorFormat, true
+		}
+	case "no-input":
+		if !rt.flag(name) && !rt.InputEnabled() {
+			return on, rt.Env.NoInput, true
+		}
+	case "yes":
+		if !rt.flag(name) && rt.AssumeYes() {
+			return on, rt.Env.AssumeYes, true
+		}
+	case "skip-validation":
+		if !rt.flag(name) && !rt.ValidationEnabled() {
+			return on, rt.Env.ValidationDisabled, true
+		}
+	}
+	return "", "", false
 }
 
 // EnvironmentMap returns a map of environment keys for color, debugging and verbosity and their values, ready for `os.Setenv`.
-func EnvironmentMap() map[string]string {
+func (rt *Runtime) EnvironmentMap() map[string]string {
 	res := map[string]string{}
 	trueString := strconv.FormatBool(true)
 
-	if !ColorEnabled() {
-		res[env.NoColor] = trueString
-	} else if isTrueIsh(os.Getenv(env.ForceColor)) {
-		res[env.ForceColor] = "always"
+	if !rt.ColorEnabled() {
+		res[rt.Env.NoColor] = trueString
+	} else if isTrueIsh(os.Getenv(rt.Env.ForceColor)) {
+		res[rt.Env.ForceColor] = "always"
+	}
+
+	if rt.DebugEnabled() {
+		res[rt.Env.Debug] = trueString
 	}
 
-	if DebugEnabled() {
-		res[env.Debug] = trueString
+	if format := rt.ErrorFormat(); format != "text" {
+		res[rt.Env.ErrorFormat] = format
 	}
 
-	if VerboseEnabled() {
-		res[env.Verbose] = trueString
-	} else if SilenceEnabled() {
-		res[env.Silent] = trueString
+	if verbosity := rt.Verbosity(); verbosity > 1 {
+		res[rt.Env.Verbose] = strconv.Itoa(verbosity)
+	} else if verbosity == 1 {
+		res[rt.Env.Verbose] = trueString
+	} else if rt.SilenceEnabled() {
+		res[rt.Env.Silent] = trueString
 	}
 
 	return res
 }
+
+// DebugEnabled tells if debugging was requested, see Process.
+func DebugEnabled() bool {
+	return Process().DebugEnabled()
+}
+
+// ValidationEnabled tells if arguments and options should be validated, see Process.
+func ValidationEnabled() bool {
+	return Process().ValidationEnabled()
+}
+
+// InputEnabled tells if prompting users for missing values is allowed, see Process.
+func InputEnabled() bool {
+	return Process().InputEnabled()
+}
+
+// AssumeYes tells if destructive commands should run without asking for confirmation, see Process.
+func AssumeYes() bool {
+	return Process().AssumeYes()
+}
+
+// VerboseEnabled tells if verbose output was requested, see Process.
+func VerboseEnabled() bool {
+	return Process().VerboseEnabled()
+}
+
+// Verbosity returns how many times verbose output was requested, see Process and Runtime.Verbosity.
+func Verbosity() int {
+	return Process().Verbosity()
+}
+
+// SilenceEnabled tells if silencing of output was requested, see Process.
+func SilenceEnabled() bool {
+	return Process().SilenceEnabled()
+}
+
+// ColorEnabled tells if colorizing output was requested, see Process.
+func ColorEnabled() bool {
+	return Process().ColorEnabled()
+}
+
+// ErrorFormat returns the format errors should be reported in, either text or json, see Process.
+func ErrorFormat() string {
+	return Process().ErrorFormat()
+}
+
+// OutputFormat returns the format requested for rendering data, see Process and Runtime.OutputFormat.
+func OutputFormat() string {
+	return Process().OutputFormat()
+}
+
+// OutputTemplate returns the Go template requested for rendering data, if any, see Process.
+func OutputTemplate() string {
+	return Process().OutputTemplate()
+}
+
+// HelpStyle returns the style to use when rendering help, see Process.
+func HelpStyle() string {
+	return Process().HelpStyle()
+}
+
+// EnvironmentMap returns a map of environment keys for color, debugging and verbosity and their values, ready for
+// `os.Setenv`, see Process.
+func EnvironmentMap() map[string]string {
+	return Process().EnvironmentMap()
+}
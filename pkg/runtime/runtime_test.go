@@ -13,6 +13,7 @@ import (
 
 	"git.rob.mx/nidito/chinampa/pkg/env"
 	. "git.rob.mx/nidito/chinampa/pkg/runtime"
+	"github.com/spf13/pflag"
 )
 
 func withEnv(t *testing.T, env map[string]string) {
@@ -132,6 +133,24 @@ func TestCombinations(t *testing.T) {
 			Func:    ColorEnabled,
 			Expects: true,
 		},
+		{
+			Env:     map[string]string{},
+			Args:    []string{"--yes=true"},
+			Func:    AssumeYes,
+			Expects: true,
+		},
+		{
+			Env:     map[string]string{},
+			Args:    []string{"--yes", "--yes=false"},
+			Func:    AssumeYes,
+			Expects: false,
+		},
+		{
+			Env:     map[string]string{},
+			Args:    []string{"--", "--yes"},
+			Func:    AssumeYes,
+			Expects: false,
+		},
 	}
 
 	for _, c := range cases {
@@ -147,6 +166,155 @@ func TestCombinations(t *testing.T) {
 	}
 }
 
+func TestVerbosity(t *testing.T) {
+	args := append([]string{}, os.Args...)
+	t.Cleanup(func() { os.Args = args })
+	cases := []struct {
+		Env     string
+		Args    []string
+		Expects int
+	}{
+		{Args: []string{}, Expects: 0},
+		{Args: []string{"-v"}, Expects: 1},
+		{Args: []string{"-vvv"}, Expects: 3},
+		{Args: []string{"--verbose", "-v"}, Expects: 2},
+		{Args: []string{"-vv", "--verbose=1"}, Expects: 1},
+		{Args: []string{"-vv", "--silent"}, Expects: 0},
+		{Args: []string{"-vx"}, Expects: 0},
+		{Args: []string{"-v", "--verbose=true"}, Expects: 2},
+		{Args: []string{"--verbose=2"}, Expects: 2},
+		{Env: "3", Args: []string{"--verbose=false"}, Expects: 0},
+		{Env: "true", Args: []string{}, Expects: 1},
+		{Env: "3", Args: []string{}, Expects: 3},
+		{Env: "3", Args: []string{"-v"}, Expects: 1},
+		{Env: "off", Args: []string{}, Expects: 0},
+	}
+
+	for _, c := range cases {
+		name := fmt.Sprintf("%s/%s", c.Env, c.Args)
+		t.Run(name, func(t *testing.T) {
+			withEnv(t, map[string]string{env.Verbose: c.Env})
+			os.Args = c.Args
+			if res := Verbosity(); res != c.Expects {
+				t.Fatalf("%s got %d wanted: %d", name, res, c.Expects)
+			}
+		})
+	}
+}
+
+func TestBind(t *testing.T) {
+	newFlags := func() (*pflag.FlagSet, *pflag.FlagSet) {
+		globals := pflag.NewFlagSet("root", pflag.ContinueOnError)
+		globals.CountP("verbose", "v", "")
+		globals.Bool("silent", false, "")
+		globals.Bool("yes", false, "")
+		globals.String("output", "plain", "")
+
+		flags := pflag.NewFlagSet("cmd", pflag.ContinueOnError)
+		flags.String("output", "", "a local option named like a global one")
+		flags.AddFlagSet(globals)
+		return flags, globals
+	}
+
+	cases := []struct {
+		Args      []string
+		Verbosity int
+		Silent    bool
+		Yes       bool
+	}{
+		{Args: []string{"-vv"}, Verbosity: 2},
+		{Args: []string{"--verbose=2"}, Verbosity: 2},
+		{Args: []string{"--verbose", "--silent"}, Silent: true},
+		{Args: []string{"--yes=true"}, Yes: true},
+		{Args: []string{"--yes=false", "--output", "file.txt"}},
+		{Args: []string{"--", "--yes", "-v"}},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprint(c.Args), func(t *testing.T) {
+			withEnv(t, map[string]string{})
+			rt := New("test", env.Current(), c.Args)
+			flags, globals := newFlags()
+			if err := flags.Parse(c.Args); err != nil {
+				t.Fatalf("could not parse: %s", err)
+			}
+			rt.Bind(flags, globals)
+
+			if rt.Verbosity() != c.Verbosity || rt.SilenceEnabled() != c.Silent || rt.AssumeYes() != c.Yes || rt.OutputFormat() != "plain" {
+				t.Fatalf("unexpected runtime: verbosity %d, silent %v, yes %v, output %s", rt.Verbosity(), rt.SilenceEnabled(), rt.AssumeYes(), rt.OutputFormat())
+			}
+		})
+	}
+
+	t.Run("unparsed", func(t *testing.T) {
+		rt := New("test", env.Current(), []string{"-vv"})
+		flags, globals := newFlags()
+		rt.Bind(flags, globals)
+		if rt.Verbosity() != 2 {
+			t.Fatalf("unparsed flags replaced arguments: %d", rt.Verbosity())
+		}
+	})
+}
+
+func TestFromEnv(t *testing.T) {
+	withEnv(t, map[string]string{env.Verbose: "3", env.NoColor: "1", env.ErrorFormat: "json"})
+	cases := []struct {
+		Args     []string
+		Flag     string
+		Value    string
+		Variable string
+	}{
+		{Flag: "verbose", Value: "3", Variable: env.Verbose},
+		{Args: []string{"--silent"}, Flag: "verbose"},
+		{Args: []string{"-v"}, Flag: "verbose"},
+		{Flag: "color", Value: "false", Variable: env.NoColor},
+		{Args: []string{"--color"}, Flag: "no-color"},
+		{Flag: "error-format", Value: "json", Variable: env.ErrorFormat},
+		{Args: []string{"--error-format", "text"}, Flag: "error-format"},
+		{Flag: "yes"},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%s/%s", c.Flag, c.Args), func(t *testing.T) {
+			value, variable, ok := New("test", env.Current(), c.Args).FromEnv(c.Flag)
+			if value != c.Value || variable != c.Variable || ok != (c.Variable != "") {
+				t.Fatalf("unexpected value %q from %q (%v)", value, variable, ok)
+			}
+		})
+	}
+}
+
+func TestErrorFormat(t *testing.T) {
+	args := append([]string{}, os.Args...)
+	t.Cleanup(func() { os.Args = args })
+	cases := []struct {
+		Env     string
+		Args    []string
+		Expects string
+	}{
+		{Args: []string{}, Expects: "text"},
+		{Args: []string{"--error-format=json"}, Expects: "json"},
+		{Args: []string{"--error-format", "JSON"}, Expects: "json"},
+		{Args: []string{"--error-format", "yaml"}, Expects: "text"},
+		{Env: "json", Args: []string{}, Expects: "json"},
+		{Env: "json", Args: []string{"--error-format=text"}, Expects: "text"},
+		{Args: []string{"--", "--error-format=json"}, Expects: "text"},
+		{Args: []string{"something", "--", "--error-format", "json"}, Expects: "text"},
+		{Args: []string{"--format", "--error-format=json"}, Expects: "text"},
+	}
+
+	for _, c := range cases {
+		name := fmt.Sprintf("%s/%s", c.Env, c.Args)
+		t.Run(name, func(t *testing.T) {
+			withEnv(t, map[string]string{env.ErrorFormat: c.Env})
+			os.Args = c.Args
+			if res := ErrorFormat(); res != c.Expects {
+				t.Fatalf("%s got %s wanted: %s", name, res, c.Expects)
+			}
+		})
+	}
+}
+
 func TestEnabled(t *testing.T) {
 	cases := []struct {
 		Name    string
@@ -177,6 +345,15 @@ func TestEnabled(t *testing.T) {
 			Func:    DebugEnabled,
 			Expects: true,
 		},
+		{
+			Name: env.NoInput,
+			Func: InputEnabled,
+		},
+		{
+			Name:    env.AssumeYes,
+			Func:    AssumeYes,
+			Expects: true,
+		},
 	}
 
 	for _, c := range cases {
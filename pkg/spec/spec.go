@@ -0,0 +1,138 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+
+/*
+Package spec loads command definitions from YAML or JSON files.
+
+A file at `db/migrate.yaml` describes the command `db migrate`, and binds it to the action registered with the name
+given by its `action` key, or `db migrate` when not set. Files for commands without a known action describe group parents.
+
+	summary: runs migrations
+	description: applies pending migrations to the database
+	action: migrate
+	arguments:
+	  - name: target
+	    description: the version to migrate to
+	options:
+	  dry-run:
+	    type: bool
+	    description: print migrations instead of applying them
+*/
+package spec
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"git.rob.mx/nidito/chinampa/pkg/command"
+	"gopkg.in/yaml.v3"
+)
+
+// Actions maps names to the actions spec files may be bound to.
+type Actions map[string]command.ContextAction
+
+// Extensions lists the file extensions of spec files.
+var Extensions = []string{".yaml", ".yml", ".json"}
+
+type file struct {
+	command.Command `yaml:",inline"`
+	// Action is the name of the action to bind this command to.
+	Action string `yaml:"action"`
+}
+
+// LoadDir returns the commands described by spec files within dir.
+func LoadDir(dir string, actions Actions) ([]*command.Command, error) {
+	return Load(os.DirFS(dir), actions)
+}
+
+// Load returns the commands described by spec files in fsys, sorted by name.
+func Load(fsys fs.FS, actions Actions) ([]*command.Command, error) {
+	cmds := []*command.Command{}
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() || !isSpec(name) {
+			return nil
+		}
+
+		cmd, err := LoadFile(fsys, name, actions)
+		if err != nil {
+			return err
+		}
+		cmds = append(cmds, cmd)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(cmds, func(i, j int) bool { return cmds[i].FullName() < cmds[j].FullName() })
+	return cmds, nil
+}
+
+// LoadFile returns the command described by the spec file at name, with its path derived from the file's location.
+func LoadFile(fsys fs.FS, name string, actions Actions) (*command.Command, error) {
+	contents, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := &file{}
+	decoder := yaml.NewDecoder(strings.NewReader(string(contents)))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(spec); err != nil {
+		return nil, fmt.Errorf("could not decode spec %s: %w", name, err)
+	}
+
+	cmd := &spec.Command
+	cmd.Path = pathFor(name)
+	if cmd.Options == nil {
+		cmd.Options = command.Options{}
+	}
+	if cmd.Arguments == nil {
+		cmd.Arguments = command.Arguments{}
+	}
+
+	if report := cmd.Validate(); len(report) > 0 {
+		issues := make([]string, 0, len(report))
+		for issue := range report {
+			issues = append(issues, issue)
+		}
+		sort.Strings(issues)
+		return nil, fmt.Errorf("invalid spec %s: %s", name, strings.Join(issues, "; "))
+	}
+
+	actionName := spec.Action
+	if actionName == "" {
+		actionName = cmd.FullName()
+	}
+
+	if action, ok := actions[actionName]; ok {
+		cmd.ContextAction = action
+	} else if spec.Action != "" {
+		return nil, fmt.Errorf("unknown action %s for spec %s", spec.Action, name)
+	}
+
+	return cmd.SetBindings(), nil
+}
+
+func isSpec(name string) bool {
+	ext := path.Ext(name)
+	for _, known := range Extensions {
+		if ext == known {
+			return true
+		}
+	}
+	return false
+}
+
+// pathFor turns a file name like `db/migrate.yaml` into a command path like `db migrate`.
+func pathFor(name string) []string {
+	return strings.Split(strings.TrimSuffix(name, path.Ext(name)), "/")
+}
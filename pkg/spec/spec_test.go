@@ -0,0 +1,139 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package spec_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"git.rob.mx/nidito/chinampa/pkg/command"
+	. "git.rob.mx/nidito/chinampa/pkg/spec"
+)
+
+func TestLoad(t *testing.T) {
+	called := []string{}
+	actions := Actions{
+		"migrate": func(ctx context.Context, cmd *command.Command) error {
+			called = append(called, cmd.FullName())
+			input := struct {
+				Batch int `option:"batch"`
+			}{}
+			if err := cmd.Decode(&input); err != nil || input.Batch != 0 {
+				t.Fatalf("unexpected batch decoded: %d (%v)", input.Batch, err)
+			}
+			return nil
+		},
+		"db status": func(ctx context.Context, cmd *command.Command) error {
+			called = append(called, cmd.FullName())
+			return nil
+		},
+	}
+
+	fsys := fstest.MapFS{
+		"db.yaml": {Data: []byte("summary: database commands\ndescription: manages the database\n")},
+		"db/migrate.yaml": {Data: []byte(`summary: runs migrations
+description: applies pending migrations
+action: migrate
+arguments:
+  - name: target
+    description: the version to migrate to
+    values:
+      static: [latest, first]
+options:
+  dry-run:
+    type: bool
+    description: print migrations instead of applying them
+  batch:
+    type: int
+    description: how many migrations to apply at once
+`)},
+		"db/status.json": {Data: []byte(`{"summary": "shows status", "description": "shows migration status"}`)},
+		"db/README.md":   {Data: []byte("not a spec")},
+	}
+
+	cmds, err := Load(fsys, actions)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	names := []string{}
+	for _, cmd := range cmds {
+		names = append(names, cmd.FullName())
+	}
+	if strings.Join(names, ",") != "db,db migrate,db status" {
+		t.Fatalf("unexpected commands loaded: %v", names)
+	}
+
+	if cmds[0].HasAction() {
+		t.Fatalf("group parent has an action")
+	}
+
+	migrate := cmds[1]
+	if migrate.Arguments[0].Name != "target" || migrate.Arguments[0].Command != migrate || !migrate.Arguments[0].Validates() {
+		t.Fatalf("unexpected arguments: %+v", migrate.Arguments[0])
+	}
+	if opt, ok := migrate.Options["dry-run"]; !ok || opt.Type != command.ValueTypeBoolean {
+		t.Fatalf("unexpected options: %+v", migrate.Options)
+	}
+
+	for _, cmd := range cmds[1:] {
+		if err := cmd.Options.Parse(cmd.FlagSet()); err != nil {
+			t.Fatalf("could not parse options: %s", err)
+		}
+		if err := cmd.ContextAction(context.Background(), cmd); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if strings.Join(called, ",") != "db migrate,db status" {
+		t.Fatalf("unexpected actions called: %v", called)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	cases := []struct {
+		Name  string
+		Spec  string
+		Error string
+	}{
+		{
+			Name:  "unknown action",
+			Spec:  "summary: a\ndescription: b\naction: missing\n",
+			Error: "unknown action missing for spec cmd.yaml",
+		},
+		{
+			Name:  "invalid",
+			Spec:  "summary: a\n",
+			Error: "invalid spec cmd.yaml: Key: 'Command.Description' Error:Field validation for 'Description' failed on the 'required' tag",
+		},
+		{
+			Name:  "empty option",
+			Spec:  "summary: a\ndescription: b\noptions:\n  foo:\n",
+			Error: "invalid spec cmd.yaml: option <foo> is empty",
+		},
+		{
+			Name:  "empty argument",
+			Spec:  "summary: a\ndescription: b\narguments:\n  -\n",
+			Error: "invalid spec cmd.yaml: argument #0 is empty",
+		},
+		{
+			Name:  "unknown field",
+			Spec:  "summary: a\ndescription: b\nsumary: typo\n",
+			Error: "could not decode spec cmd.yaml",
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			_, err := Load(fstest.MapFS{"cmd.yaml": {Data: []byte(c.Spec)}}, Actions{})
+			if err == nil {
+				t.Fatalf("expected error, got none")
+			}
+			if !strings.HasPrefix(err.Error(), c.Error) {
+				t.Fatalf("could not find error <%s> got <%s>", c.Error, err)
+			}
+		})
+	}
+}
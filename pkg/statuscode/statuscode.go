@@ -9,9 +9,13 @@ and https://tldp.org/LDP/abs/html/exitcodes.html
 */
 package statuscode
 
+import "syscall"
+
 const (
 	// Ok means everything is fine.
 	Ok = 0
+	// Cancelled means the user declined to continue, i.e. when asked to confirm running a destructive command.
+	Cancelled = 1
 	// RenderHelp provides answers to life, the universe and everything; also, renders help.
 	RenderHelp = 42
 	// Usage means bad arguments were provided by the user.
@@ -22,4 +26,15 @@ const (
 	ConfigError = 78
 	// NotFound means a sub-command not was found.
 	NotFound = 127
+	// Signaled is added to the number of the signal that terminated a program.
+	Signaled = 128
+	// Interrupted means the program was terminated by SIGINT.
+	Interrupted = Signaled + int(syscall.SIGINT)
+	// Terminated means the program was terminated by SIGTERM.
+	Terminated = Signaled + int(syscall.SIGTERM)
 )
+
+// ForSignal returns the conventional exit code, 128+n, for a program terminated by signal n.
+func ForSignal(sig syscall.Signal) int {
+	return Signaled + int(sig)
+}
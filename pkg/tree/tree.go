@@ -30,8 +30,10 @@ func (t *CommandTree) Traverse(fn func(cmd *command.Command) error) error {
 }
 
 var tree *CommandTree
+var commands *registry.CommandRegistry
 
 func Build(cc *cobra.Command, depth int) {
+	commands = registry.ForCobra(cc)
 	root := registry.FromCobra(cc)
 	if root == nil && cc.Root() == cc {
 		root = command.Root
@@ -85,5 +87,8 @@ func ChildrenNames() []string {
 }
 
 func CommandList() []*command.Command {
-	return registry.CommandList()
+	if commands == nil {
+		return []*command.Command{}
+	}
+	return commands.CommandList()
 }
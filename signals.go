@@ -0,0 +1,50 @@
+// Copyright © 2022 Roberto Hidalgo <chinampa@un.rob.mx>
+// SPDX-License-Identifier: Apache-2.0
+package chinampa
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"git.rob.mx/nidito/chinampa/pkg/errors"
+	"git.rob.mx/nidito/chinampa/pkg/logger"
+	"git.rob.mx/nidito/chinampa/pkg/statuscode"
+)
+
+// withSignals returns a context that is cancelled upon receiving the first SIGINT or SIGTERM, with an
+// errors.Signaled as its cause. A second signal exits the program right away, with the conventional 128+n status.
+func withSignals(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	received := make(chan os.Signal, 2)
+	stopped := make(chan struct{})
+	signal.Notify(received, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-received:
+			logger.Debugf("received %s, cancelling; send again to exit right away", sig)
+			cancel(errors.Signaled{Signal: sig})
+		case <-stopped:
+			return
+		}
+
+		select {
+		case sig := <-received:
+			logger.Debugf("received %s again, exiting", sig)
+			code := statuscode.Interrupted
+			if s, ok := sig.(syscall.Signal); ok {
+				code = statuscode.ForSignal(s)
+			}
+			os.Exit(code)
+		case <-stopped:
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(received)
+		close(stopped)
+		cancel(nil)
+	}
+}